@@ -0,0 +1,64 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Public IP",type=string,JSONPath=`.status.publicIPAddress`
+// +kubebuilder:printcolumn:name="Private IP",type=string,JSONPath=`.status.assignment.privateIPAddress`
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.status.assignment.namespace`
+// +kubebuilder:printcolumn:name="Pod",type=string,JSONPath=`.status.assignment.podName`
+// +kubebuilder:printcolumn:name="Expires In",type=string,JSONPath=`.status.remainingLifetime`,priority=1
+
+// ClusterReservedIP is the Schema for the cluster-scoped ClusterReservedIPs API.
+// It behaves like a ReservedIP, but is not bound to a namespace and can be
+// assigned to pods in any namespace via spec.assignment.namespace.
+type ClusterReservedIP struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReservedIPSpec   `json:"spec,omitempty"`
+	Status ReservedIPStatus `json:"status,omitempty"`
+}
+
+// GetSpec returns the spec of the ClusterReservedIP
+func (in *ClusterReservedIP) GetSpec() *ReservedIPSpec {
+	return &in.Spec
+}
+
+// GetStatus returns the status of the ClusterReservedIP
+func (in *ClusterReservedIP) GetStatus() *ReservedIPStatus {
+	return &in.Status
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterReservedIPList contains a list of ClusterReservedIP
+type ClusterReservedIPList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterReservedIP `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterReservedIP{}, &ClusterReservedIPList{})
+}
@@ -0,0 +1,99 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OCIAccountAuthType describes how the operator authenticates for an account
+// +kubebuilder:validation:Enum=APIKey;InstancePrincipal;ResourcePrincipal
+type OCIAccountAuthType string
+
+const (
+	// OCIAccountAPIKey uses the API signing key stored in the account's
+	// secret.
+	OCIAccountAPIKey OCIAccountAuthType = "APIKey"
+	// OCIAccountInstancePrincipal uses the instance principal of the node the
+	// operator runs on.
+	OCIAccountInstancePrincipal OCIAccountAuthType = "InstancePrincipal"
+	// OCIAccountResourcePrincipal uses the resource principal the operator's
+	// environment provides.
+	OCIAccountResourcePrincipal OCIAccountAuthType = "ResourcePrincipal"
+)
+
+// Keys of the secret referenced by an OCIAccount with the APIKey auth type
+const (
+	OCIAccountTenancyKey     = "tenancy"
+	OCIAccountUserKey        = "user"
+	OCIAccountFingerprintKey = "fingerprint"
+	OCIAccountPrivateKeyKey  = "privateKey"
+	OCIAccountPassphraseKey  = "passphrase"
+)
+
+// OCIAccountSpec defines how to talk to an OCI tenancy and region
+type OCIAccountSpec struct {
+	// OCI region, e.g. us-ashburn-1.
+	Region string `json:"region"`
+
+	// How to authenticate. Defaults to APIKey.
+	// +optional
+	AuthType OCIAccountAuthType `json:"authType,omitempty"`
+
+	// Secret containing the keys tenancy, user, fingerprint, privateKey and
+	// optionally passphrase. Required for the APIKey auth type. The secret
+	// must be in the operator's -oci-account-secret-namespace.
+	// +optional
+	SecretRef *corev1.SecretReference `json:"secretRef,omitempty"`
+
+	// Namespaces whose ReservedIPs may use the account. If unset, only
+	// ClusterReservedIPs may use it.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// OCI compartment to create ReservedIPs in.
+	CompartmentID string `json:"compartmentID"`
+
+	// OCI Virtual Cloud Network (VCN) the pods' private IPs are in.
+	VcnID string `json:"vcnID"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Region",type=string,JSONPath=`.spec.region`
+// +kubebuilder:printcolumn:name="Auth Type",type=string,JSONPath=`.spec.authType`
+
+// OCIAccount is the Schema for the OCIAccounts API
+type OCIAccount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OCIAccountSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OCIAccountList contains a list of OCIAccount
+type OCIAccountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OCIAccount `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OCIAccount{}, &OCIAccountList{})
+}
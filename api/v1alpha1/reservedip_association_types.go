@@ -20,18 +20,113 @@ import (
 )
 
 type ReservedIPAssociationSpec struct {
-	Assignment     *ReservedIPAssignment `json:"assignment,omitempty"`
-	ReservedIPName string                `json:"reservedIPName,omitempty"`
+	Assignment *ReservedIPAssignment `json:"assignment,omitempty"`
+
+	// Name of the ReservedIP to assign. Exactly one of reservedIPName and
+	// reservedIPSelector needs to be given.
+	// +optional
+	ReservedIPName string `json:"reservedIPName,omitempty"`
+
+	// Selects the ReservedIP to assign by label. The first allocated and
+	// unassigned ReservedIP (ordered by name) matching the selector is chosen
+	// and kept until the association is deleted.
+	// +optional
+	ReservedIPSelector *metav1.LabelSelector `json:"reservedIPSelector,omitempty"`
+
+	// Ties the lifetime of the association to the pod given in
+	// spec.assignment.podName, or to the pods selected by
+	// podLifecycle.podSelector.
+	// +optional
+	PodLifecycle *PodLifecycle `json:"podLifecycle,omitempty"`
+}
+
+// PodLifecyclePolicy describes how an association follows its target pod
+// +kubebuilder:validation:Enum=None;OwnerReference;Follow
+type PodLifecyclePolicy string
+
+const (
+	// PodLifecycleNone keeps the association independent of the pod.
+	PodLifecycleNone PodLifecyclePolicy = "None"
+	// PodLifecycleOwnerReference makes the pod the owner of the association,
+	// so it is garbage collected together with the pod.
+	PodLifecycleOwnerReference PodLifecyclePolicy = "OwnerReference"
+	// PodLifecycleFollow deletes the association once the pod, or all pods
+	// selected by the pod selector, have been gone or terminated for longer
+	// than the grace period.
+	PodLifecycleFollow PodLifecyclePolicy = "Follow"
+)
+
+type PodLifecycle struct {
+	// Defaults to None.
+	// +optional
+	Policy PodLifecyclePolicy `json:"policy,omitempty"`
+
+	// How long to wait after the pod is gone before deleting the association
+	// with the Follow policy. A pod coming back under the same name within
+	// this period, e.g. a restarted StatefulSet pod, keeps the ReservedIP.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	GracePeriodSeconds *int32 `json:"gracePeriodSeconds,omitempty"`
+
+	// Follows the pods matching the selector instead of
+	// spec.assignment.podName with the Follow policy: the association is kept
+	// as long as one of them is running.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+const (
+	// AssociationAnnotation is set on ReservedIPs whose assignment is owned by
+	// a ReservedIPAssociation and contains the name of the association.
+	AssociationAnnotation = "oci.k8s.logmein.com/association"
+
+	// ForceAbandonAnnotation set to "true" on a ReservedIPAssociation that is
+	// being deleted makes the operator remove its finalizer without
+	// unassigning the ReservedIP.
+	ForceAbandonAnnotation = "oci.k8s.logmein.com/force-abandon"
+
+	// ReservedIPAssociationBound is the condition type telling whether the
+	// association's assignment was written to the ReservedIP.
+	ReservedIPAssociationBound = "Bound"
+)
+
+type ReservedIPAssociationStatus struct {
+	// Current phase of the association.
+	//
+	// Pending: the assignment couldn't be written to the ReservedIP yet, see
+	// the Bound condition for the reason.
+	// Bound: the assignment was written to the ReservedIP.
+	// Assigned: the ReservedIP is assigned according to the association.
+	Phase string `json:"phase,omitempty"`
+
+	// Name of the ReservedIP the association is bound to.
+	// +optional
+	ReservedIPName string `json:"reservedIPName,omitempty"`
+
+	// Time the target pod was first seen gone or terminated, when following
+	// the pod.
+	// +optional
+	PodGoneSince *metav1.Time `json:"podGoneSince,omitempty"`
+
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Pod Name",type=string,JSONPath=`.spec.assignment.podName`
-// +kubebuilder:printcolumn:name="ReservedIP Name",type=string,JSONPath=`.spec.reservedIPName`
+// +kubebuilder:printcolumn:name="ReservedIP Name",type=string,JSONPath=`.status.reservedIPName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 type ReservedIPAssociation struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ReservedIPAssociationSpec `json:"spec,omitempty"`
+	Spec   ReservedIPAssociationSpec   `json:"spec,omitempty"`
+	Status ReservedIPAssociationStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
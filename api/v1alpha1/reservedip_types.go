@@ -21,15 +21,52 @@ import (
 
 type ReservedIPAssignment struct {
 	// +optional
-	PodName          string `json:"podName,omitempty"`
+	PodName string `json:"podName,omitempty"`
+
+	// Namespace of the pod given in podName.
+	//
+	// Only used by ClusterReservedIPs; ReservedIPs can only be assigned to
+	// pods in their own namespace.
+	//
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
 	PrivateIPAddress string `json:"privateIPAddress,omitempty"`
+
+	// Name of a coordination.k8s.io Lease in the pod namespace. The
+	// ReservedIP is assigned to the pod holding the Lease and follows
+	// leadership changes.
+	//
+	// In the status, podName contains the pod that held the Lease when the
+	// ReservedIP was assigned.
+	//
+	// +optional
+	LeaseName string `json:"leaseName,omitempty"`
+
+	// Decides which ReservedIP is assigned when several ReservedIPs or
+	// ClusterReservedIPs target the same pod, private IP or Lease: the one
+	// with the highest priority wins, then the oldest one. The others get the
+	// Conflict condition and are left alone until the winner goes away.
+	//
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Allows unassigning a reserved public IP from the target private IP that
+	// wasn't allocated by the operator. Without it, the ReservedIP gets the
+	// ForeignPublicIP condition instead.
+	//
+	// +optional
+	AllowTakeover bool `json:"allowTakeover,omitempty"`
 }
 
 func (r ReservedIPAssignment) MatchesSpec(spec ReservedIPAssignment) bool {
+	if spec.LeaseName != "" {
+		return spec.LeaseName == r.LeaseName && spec.Namespace == r.Namespace
+	}
 	if spec.PodName == "" {
 		return spec.PrivateIPAddress == r.PrivateIPAddress
 	}
-	return spec.PodName == r.PodName
+	return spec.PodName == r.PodName && spec.Namespace == r.Namespace
 }
 
 // ReservedIPSpec defines the desired state of EIP
@@ -44,11 +81,138 @@ type ReservedIPSpec struct {
 	PublicIPPoolID  string `json:"publicIPPoolID,omitempty"`
 	PublicIPAddress string `json:"publicIPAddress,omitempty"`
 
+	// OCI compartment the ReservedIP is created in. Defaults to the
+	// compartment the operator was started with.
+	// +optional
+	CompartmentID string `json:"compartmentID,omitempty"`
+
 	// Tags that will be applied to the created EIP.
 	// +optional
 	Tags *map[string]string `json:"tags,omitempty"`
+
+	// Name of the ReservedIPClass this ReservedIP was provisioned from, if
+	// any.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// What happens to the ReservedIP when the ReservedIPClaim bound to it is
+	// deleted. Defaults to Delete.
+	// +optional
+	ReclaimPolicy ReservedIPReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// Stops the operator from changing the public IP in OCI, e.g. while it is
+	// changed manually. Setting the oci.k8s.logmein.com/paused annotation to
+	// "true" has the same effect.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Name of the OCIAccount to allocate the ReservedIP with. Defaults to the
+	// tenancy, region, compartment and VCN the operator was started with.
+	// +optional
+	AccountName string `json:"accountName,omitempty"`
+
+	// What to do when the node of the assigned pod becomes NotReady or is
+	// cordoned. Defaults to the operator's -node-evacuation-policy.
+	// +optional
+	Evacuation *Evacuation `json:"evacuation,omitempty"`
+
+	// How long after its creation the ReservedIP expires, e.g. 72h. Expired
+	// ReservedIPs are deleted, which releases the public IP, or only
+	// unassigned if the reclaim policy is Retain. A warning event is recorded
+	// an hour (or a quarter of the TTL, if shorter) before.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
 }
 
+// EvacuationPolicy describes what happens to an assigned ReservedIP when the
+// node of its pod fails or is drained
+// +kubebuilder:validation:Enum=Wait;Unassign;MoveToStandby
+type EvacuationPolicy string
+
+const (
+	// EvacuationWait leaves the ReservedIP assigned until the pod is gone.
+	EvacuationWait EvacuationPolicy = "Wait"
+	// EvacuationUnassign unassigns the ReservedIP immediately.
+	EvacuationUnassign EvacuationPolicy = "Unassign"
+	// EvacuationMoveToStandby assigns the ReservedIP to a Ready pod matching
+	// standbyPodSelector on a healthy node.
+	EvacuationMoveToStandby EvacuationPolicy = "MoveToStandby"
+)
+
+type Evacuation struct {
+	// +optional
+	Policy EvacuationPolicy `json:"policy,omitempty"`
+
+	// Selects standby pods in the namespace of the assigned pod for the
+	// MoveToStandby policy.
+	// +optional
+	StandbyPodSelector *metav1.LabelSelector `json:"standbyPodSelector,omitempty"`
+}
+
+const (
+	// DryRunAnnotation set to "true" makes the operator only record the OCI
+	// calls it would make for a ReservedIP or ClusterReservedIP instead of
+	// making them.
+	DryRunAnnotation = "oci.k8s.logmein.com/dry-run"
+
+	// PausedAnnotation set to "true" pauses the reconciliation of a
+	// ReservedIP or ClusterReservedIP like spec.paused.
+	PausedAnnotation = "oci.k8s.logmein.com/paused"
+
+	// ForceReleaseAnnotation set to "true" on a ReservedIP or
+	// ClusterReservedIP that is being deleted makes the operator remove its
+	// finalizer without releasing the public IP in OCI, e.g. because the
+	// credentials were revoked or the compartment was deleted.
+	ForceReleaseAnnotation = "oci.k8s.logmein.com/force-release"
+
+	// EvacuatedAnnotation is set by the operator on a ReservedIP or
+	// ClusterReservedIP it evacuated from a failed or drained node. It
+	// records the node and the assignment from before the evacuation, which
+	// is restored once the node is healthy again.
+	EvacuatedAnnotation = "oci.k8s.logmein.com/evacuated"
+
+	// ReservedIPPaused is the condition type telling whether the
+	// reconciliation of a ReservedIP is paused.
+	ReservedIPPaused = "Paused"
+
+	// ReservedIPConflict is the condition type telling whether a ReservedIP
+	// isn't assigned because another one targets the same pod, private IP or
+	// Lease.
+	ReservedIPConflict = "Conflict"
+
+	// ReservedIPForeignPublicIP is the condition type telling whether a
+	// ReservedIP isn't assigned because the target private IP has a reserved
+	// public IP that wasn't allocated by the operator.
+	ReservedIPForeignPublicIP = "ForeignPublicIP"
+
+	// ReservedIPPolicyViolation is the condition type telling whether a
+	// ReservedIP violates a ReservedIPPolicy and is therefore not changed in
+	// OCI.
+	ReservedIPPolicyViolation = "PolicyViolation"
+
+	// ReservedIPQuotaExceeded is the condition type telling whether a
+	// ReservedIP isn't allocated or assigned because it would exceed a
+	// ReservedIPQuota.
+	ReservedIPQuotaExceeded = "QuotaExceeded"
+
+	// ReservedIPExpiring is the condition type telling whether the TTL of a
+	// ReservedIP is about to expire or has expired.
+	ReservedIPExpiring = "Expiring"
+)
+
+// ReservedIPReclaimPolicy describes what happens to a ReservedIP when it is
+// released from its claim.
+// +kubebuilder:validation:Enum=Delete;Retain
+type ReservedIPReclaimPolicy string
+
+const (
+	// ReservedIPReclaimDelete deletes the ReservedIP (and thereby releases the
+	// OCI public IP) when its claim is deleted.
+	ReservedIPReclaimDelete ReservedIPReclaimPolicy = "Delete"
+	// ReservedIPReclaimRetain keeps the ReservedIP when its claim is deleted.
+	ReservedIPReclaimRetain ReservedIPReclaimPolicy = "Retain"
+)
+
 // ReservedIPStatus defines the observed state of EIP
 type ReservedIPStatus struct {
 	// Current state of the EIP object.
@@ -62,6 +226,8 @@ type ReservedIPStatus struct {
 	//                   |             |
 	//   *end*:          |             |
 	//  releasing <------/-------------/
+	//
+	// ReservedIPs in any state move to releasing when deleted.
 	State string `json:"state"`
 
 	OCID            string `json:"OCID,omitempty"`
@@ -71,6 +237,22 @@ type ReservedIPStatus struct {
 	PrivateIPAddressID string                `json:"privateIPAddressID,omitempty"`
 
 	EphemeralIPWasUnassigned bool `json:"ephemeralIPWasUnassigned"`
+
+	// When spec.ttl expires.
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+
+	// Time left until spec.ttl expires, e.g. 5h. Updated periodically, more
+	// often as the expiration approaches.
+	// +optional
+	RemainingLifetime string `json:"remainingLifetime,omitempty"`
+
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
@@ -79,6 +261,7 @@ type ReservedIPStatus struct {
 // +kubebuilder:printcolumn:name="Public IP",type=string,JSONPath=`.status.publicIPAddress`
 // +kubebuilder:printcolumn:name="Private IP",type=string,JSONPath=`.status.assignment.privateIPAddress`
 // +kubebuilder:printcolumn:name="Pod",type=string,JSONPath=`.status.assignment.podName`
+// +kubebuilder:printcolumn:name="Expires In",type=string,JSONPath=`.status.remainingLifetime`,priority=1
 
 // ReservedIP is the Schema for the ReservedIPs API
 type ReservedIP struct {
@@ -89,6 +272,16 @@ type ReservedIP struct {
 	Status ReservedIPStatus `json:"status,omitempty"`
 }
 
+// GetSpec returns the spec of the ReservedIP
+func (in *ReservedIP) GetSpec() *ReservedIPSpec {
+	return &in.Spec
+}
+
+// GetStatus returns the status of the ReservedIP
+func (in *ReservedIP) GetStatus() *ReservedIPStatus {
+	return &in.Status
+}
+
 // +kubebuilder:object:root=true
 
 // ReservedIPList contains a list of ReservedIP
@@ -0,0 +1,95 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BoundClaimAnnotation is set on ReservedIPs bound to a ReservedIPClaim and
+	// contains the name of the claim.
+	BoundClaimAnnotation = "oci.k8s.logmein.com/bound-claim"
+
+	// ProvisionedForClaimLabel is set on ReservedIPs provisioned for a
+	// ReservedIPClaim and contains the UID of the claim. Only these are
+	// deleted with the claim.
+	ProvisionedForClaimLabel = "oci.k8s.logmein.com/provisioned-for-claim"
+)
+
+// ReservedIPClaimSpec defines the desired state of ReservedIPClaim
+type ReservedIPClaimSpec struct {
+	// Name of the ReservedIPClass to provision a ReservedIP from. If not
+	// given, the default class is used.
+	// +optional
+	ReservedIPClassName string `json:"reservedIPClassName,omitempty"`
+
+	// Name of an existing ReservedIP in the same namespace to bind to instead
+	// of provisioning a new one.
+	// +optional
+	ReservedIPName string `json:"reservedIPName,omitempty"`
+}
+
+// ReservedIPClaimStatus defines the observed state of ReservedIPClaim
+type ReservedIPClaimStatus struct {
+	// Current phase of the claim.
+	//
+	// Pending: no ReservedIP is bound yet.
+	// Bound: the claim is bound to a ReservedIP.
+	// Lost: the bound ReservedIP doesn't exist anymore.
+	Phase string `json:"phase,omitempty"`
+
+	// Human readable reason for the current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Name of the ReservedIP bound to this claim.
+	// +optional
+	ReservedIPName string `json:"reservedIPName,omitempty"`
+
+	// Public IP address of the bound ReservedIP, once allocated.
+	// +optional
+	PublicIPAddress string `json:"publicIPAddress,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="ReservedIP",type=string,JSONPath=`.status.reservedIPName`
+// +kubebuilder:printcolumn:name="Public IP",type=string,JSONPath=`.status.publicIPAddress`
+// +kubebuilder:printcolumn:name="Class",type=string,JSONPath=`.spec.reservedIPClassName`
+
+// ReservedIPClaim is the Schema for the ReservedIPClaims API
+type ReservedIPClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReservedIPClaimSpec   `json:"spec,omitempty"`
+	Status ReservedIPClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReservedIPClaimList contains a list of ReservedIPClaim
+type ReservedIPClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReservedIPClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReservedIPClaim{}, &ReservedIPClaimList{})
+}
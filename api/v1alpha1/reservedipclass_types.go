@@ -0,0 +1,83 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// IsDefaultReservedIPClassAnnotation marks a ReservedIPClass as the one to
+	// use for ReservedIPClaims that don't name a class.
+	IsDefaultReservedIPClassAnnotation = "reservedipclass.oci.k8s.logmein.com/is-default-class"
+)
+
+// ReservedIPClassSpec defines how ReservedIPs of a class are provisioned
+type ReservedIPClassSpec struct {
+	// OCI public IP pool to allocate ReservedIPs from.
+	// +optional
+	PublicIPPoolID string `json:"publicIPPoolID,omitempty"`
+
+	// OCI compartment to create ReservedIPs in. Defaults to the compartment
+	// the operator was started with.
+	// +optional
+	CompartmentID string `json:"compartmentID,omitempty"`
+
+	// OCIAccount to provision ReservedIPs with. Defaults to the operator's
+	// own account.
+	// +optional
+	AccountName string `json:"accountName,omitempty"`
+
+	// Tags that will be applied to provisioned ReservedIPs.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// What happens to a provisioned ReservedIP when its claim is deleted.
+	// Defaults to Delete.
+	// +optional
+	ReclaimPolicy ReservedIPReclaimPolicy `json:"reclaimPolicy,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Pool",type=string,JSONPath=`.spec.publicIPPoolID`
+// +kubebuilder:printcolumn:name="Reclaim Policy",type=string,JSONPath=`.spec.reclaimPolicy`
+
+// ReservedIPClass is the Schema for the ReservedIPClasses API
+type ReservedIPClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReservedIPClassSpec `json:"spec,omitempty"`
+}
+
+// IsDefault returns whether the class is annotated as default class
+func (in *ReservedIPClass) IsDefault() bool {
+	return in.Annotations[IsDefaultReservedIPClassAnnotation] == "true"
+}
+
+// +kubebuilder:object:root=true
+
+// ReservedIPClassList contains a list of ReservedIPClass
+type ReservedIPClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReservedIPClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReservedIPClass{}, &ReservedIPClassList{})
+}
@@ -0,0 +1,104 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FailoverAnnotation is set on ReservedIPs whose assignment is managed by
+	// a ReservedIPFailover and contains the name of the failover.
+	FailoverAnnotation = "oci.k8s.logmein.com/failover"
+)
+
+// FailoverPolicy describes how the active pod of a ReservedIPFailover is chosen
+// +kubebuilder:validation:Enum=PreferReady;Ordered;Sticky
+type FailoverPolicy string
+
+const (
+	// FailoverPreferReady keeps the ReservedIP on the active pod as long as it
+	// is Ready and moves it to the oldest Ready pod otherwise.
+	FailoverPreferReady FailoverPolicy = "PreferReady"
+	// FailoverOrdered always moves the ReservedIP to the Ready pod ranked
+	// highest in spec.priority, falling back to pod name order.
+	FailoverOrdered FailoverPolicy = "Ordered"
+	// FailoverSticky keeps the ReservedIP on the active pod as long as it is
+	// Ready, like PreferReady, but also while it is NotReady and no other pod
+	// is Ready, until it is deleted or terminated.
+	FailoverSticky FailoverPolicy = "Sticky"
+)
+
+// ReservedIPFailoverSpec defines the desired state of ReservedIPFailover
+type ReservedIPFailoverSpec struct {
+	// Name of the ReservedIP in the same namespace to keep on a healthy pod.
+	ReservedIPName string `json:"reservedIPName"`
+
+	// Selects the pods the ReservedIP can be assigned to.
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+
+	// Defaults to PreferReady.
+	// +optional
+	Policy FailoverPolicy `json:"policy,omitempty"`
+
+	// Pod names in order of preference, used by the Ordered policy. Pods not
+	// listed come after the listed ones, ordered by name.
+	// +optional
+	Priority []string `json:"priority,omitempty"`
+}
+
+// ReservedIPFailoverStatus defines the observed state of ReservedIPFailover
+type ReservedIPFailoverStatus struct {
+	// Pod the ReservedIP is currently assigned to.
+	// +optional
+	ActivePod string `json:"activePod,omitempty"`
+
+	// Last time the ReservedIP was moved to another pod.
+	// +optional
+	LastFailoverTime *metav1.Time `json:"lastFailoverTime,omitempty"`
+
+	// Human readable reason why the failover can't do its job, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ReservedIP",type=string,JSONPath=`.spec.reservedIPName`
+// +kubebuilder:printcolumn:name="Policy",type=string,JSONPath=`.spec.policy`
+// +kubebuilder:printcolumn:name="Active Pod",type=string,JSONPath=`.status.activePod`
+
+// ReservedIPFailover is the Schema for the ReservedIPFailovers API
+type ReservedIPFailover struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReservedIPFailoverSpec   `json:"spec,omitempty"`
+	Status ReservedIPFailoverStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReservedIPFailoverList contains a list of ReservedIPFailover
+type ReservedIPFailoverList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReservedIPFailover `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReservedIPFailover{}, &ReservedIPFailoverList{})
+}
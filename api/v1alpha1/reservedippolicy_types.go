@@ -0,0 +1,98 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReservedIPAssignmentType is the kind of target a ReservedIP is assigned to
+// +kubebuilder:validation:Enum=Pod;PrivateIPAddress;Lease
+type ReservedIPAssignmentType string
+
+const (
+	// AssignmentTypePod assigns to spec.assignment.podName.
+	AssignmentTypePod ReservedIPAssignmentType = "Pod"
+	// AssignmentTypePrivateIPAddress assigns to
+	// spec.assignment.privateIPAddress.
+	AssignmentTypePrivateIPAddress ReservedIPAssignmentType = "PrivateIPAddress"
+	// AssignmentTypeLease assigns to the holder of spec.assignment.leaseName.
+	AssignmentTypeLease ReservedIPAssignmentType = "Lease"
+)
+
+// ReservedIPPolicySpec restricts the ReservedIPs in the selected namespaces.
+// Lists that are empty don't restrict anything.
+type ReservedIPPolicySpec struct {
+	// Namespaces the policy applies to. Selects all namespaces if not given.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Kinds of targets ReservedIPs may be assigned to.
+	// +optional
+	AllowedAssignmentTypes []ReservedIPAssignmentType `json:"allowedAssignmentTypes,omitempty"`
+
+	// CIDRs spec.assignment.privateIPAddress must be in, e.g. 10.0.16.0/20.
+	// +optional
+	AllowedPrivateIPCIDRs []string `json:"allowedPrivateIPCIDRs,omitempty"`
+
+	// OCIDs of the public IP pools spec.publicIPPoolID may reference.
+	// ReservedIPs from Oracle's pool are always allowed.
+	// +optional
+	AllowedPublicIPPoolIDs []string `json:"allowedPublicIPPoolIDs,omitempty"`
+
+	// OCIDs of the compartments ReservedIPs may be created in: the
+	// compartment of spec.compartmentID or else of the OCIAccount in
+	// spec.accountName. ReservedIPs in the operator's default compartment are
+	// always allowed.
+	// +optional
+	AllowedCompartmentIDs []string `json:"allowedCompartmentIDs,omitempty"`
+
+	// Names of the OCIAccounts spec.accountName may reference. ReservedIPs
+	// using the operator's own credentials are always allowed.
+	// +optional
+	AllowedAccountNames []string `json:"allowedAccountNames,omitempty"`
+
+	// Whether ReservedIPs may set spec.assignment.allowTakeover to unassign
+	// public IPs the operator didn't allocate. Not restricted if not given.
+	// +optional
+	AllowTakeover *bool `json:"allowTakeover,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ReservedIPPolicy is the Schema for the ReservedIPPolicies API. A ReservedIP
+// has to satisfy all policies selecting its namespace. ClusterReservedIPs
+// aren't restricted by policies.
+type ReservedIPPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReservedIPPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReservedIPPolicyList contains a list of ReservedIPPolicy
+type ReservedIPPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReservedIPPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReservedIPPolicy{}, &ReservedIPPolicyList{})
+}
@@ -0,0 +1,86 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReservedIPQuotaLimits are the maximum numbers of ReservedIPs. Limits that
+// aren't given are unlimited.
+type ReservedIPQuotaLimits struct {
+	// Maximum number of ReservedIPs.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReservedIPs *int32 `json:"reservedIPs,omitempty"`
+
+	// Maximum number of ReservedIPs with spec.assignment.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	AssignedReservedIPs *int32 `json:"assignedReservedIPs,omitempty"`
+}
+
+// ReservedIPQuotaSpec defines the desired state of ReservedIPQuota
+type ReservedIPQuotaSpec struct {
+	// Only count ReservedIPs allocated from this public IP pool. Counts the
+	// ReservedIPs of all pools if not given.
+	// +optional
+	PublicIPPoolID string `json:"publicIPPoolID,omitempty"`
+
+	Hard ReservedIPQuotaLimits `json:"hard"`
+}
+
+// ReservedIPQuotaUsage are the numbers of ReservedIPs counted by a quota
+type ReservedIPQuotaUsage struct {
+	ReservedIPs         int32 `json:"reservedIPs"`
+	AssignedReservedIPs int32 `json:"assignedReservedIPs"`
+}
+
+// ReservedIPQuotaStatus defines the observed state of ReservedIPQuota
+type ReservedIPQuotaStatus struct {
+	// +optional
+	Used ReservedIPQuotaUsage `json:"used,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ReservedIPs",type=integer,JSONPath=`.status.used.reservedIPs`
+// +kubebuilder:printcolumn:name="Hard",type=integer,JSONPath=`.spec.hard.reservedIPs`
+// +kubebuilder:printcolumn:name="Assigned",type=integer,JSONPath=`.status.used.assignedReservedIPs`
+// +kubebuilder:printcolumn:name="Hard Assigned",type=integer,JSONPath=`.spec.hard.assignedReservedIPs`
+
+// ReservedIPQuota is the Schema for the ReservedIPQuotas API. It limits the
+// number of ReservedIPs in its namespace.
+type ReservedIPQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReservedIPQuotaSpec   `json:"spec,omitempty"`
+	Status ReservedIPQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReservedIPQuotaList contains a list of ReservedIPQuota
+type ReservedIPQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReservedIPQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReservedIPQuota{}, &ReservedIPQuotaList{})
+}
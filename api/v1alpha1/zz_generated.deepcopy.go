@@ -29,9 +29,198 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReservedIP) DeepCopyInto(out *ClusterReservedIP) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReservedIP.
+func (in *ClusterReservedIP) DeepCopy() *ClusterReservedIP {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReservedIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterReservedIP) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReservedIPList) DeepCopyInto(out *ClusterReservedIPList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterReservedIP, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReservedIPList.
+func (in *ClusterReservedIPList) DeepCopy() *ClusterReservedIPList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReservedIPList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterReservedIPList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Evacuation) DeepCopyInto(out *Evacuation) {
+	*out = *in
+	if in.StandbyPodSelector != nil {
+		in, out := &in.StandbyPodSelector, &out.StandbyPodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Evacuation.
+func (in *Evacuation) DeepCopy() *Evacuation {
+	if in == nil {
+		return nil
+	}
+	out := new(Evacuation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCIAccount) DeepCopyInto(out *OCIAccount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCIAccount.
+func (in *OCIAccount) DeepCopy() *OCIAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(OCIAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OCIAccount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCIAccountList) DeepCopyInto(out *OCIAccountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OCIAccount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCIAccountList.
+func (in *OCIAccountList) DeepCopy() *OCIAccountList {
+	if in == nil {
+		return nil
+	}
+	out := new(OCIAccountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OCIAccountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCIAccountSpec) DeepCopyInto(out *OCIAccountSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCIAccountSpec.
+func (in *OCIAccountSpec) DeepCopy() *OCIAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OCIAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodLifecycle) DeepCopyInto(out *PodLifecycle) {
+	*out = *in
+	if in.GracePeriodSeconds != nil {
+		in, out := &in.GracePeriodSeconds, &out.GracePeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodLifecycle.
+func (in *PodLifecycle) DeepCopy() *PodLifecycle {
+	if in == nil {
+		return nil
+	}
+	out := new(PodLifecycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReservedIP) DeepCopyInto(out *ReservedIP) {
 	*out = *in
@@ -80,6 +269,7 @@ func (in *ReservedIPAssociation) DeepCopyInto(out *ReservedIPAssociation) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPAssociation.
@@ -140,6 +330,16 @@ func (in *ReservedIPAssociationSpec) DeepCopyInto(out *ReservedIPAssociationSpec
 		*out = new(ReservedIPAssignment)
 		**out = **in
 	}
+	if in.ReservedIPSelector != nil {
+		in, out := &in.ReservedIPSelector, &out.ReservedIPSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodLifecycle != nil {
+		in, out := &in.PodLifecycle, &out.PodLifecycle
+		*out = new(PodLifecycle)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPAssociationSpec.
@@ -153,31 +353,84 @@ func (in *ReservedIPAssociationSpec) DeepCopy() *ReservedIPAssociationSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReservedIPList) DeepCopyInto(out *ReservedIPList) {
+func (in *ReservedIPAssociationStatus) DeepCopyInto(out *ReservedIPAssociationStatus) {
+	*out = *in
+	if in.PodGoneSince != nil {
+		in, out := &in.PodGoneSince, &out.PodGoneSince
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPAssociationStatus.
+func (in *ReservedIPAssociationStatus) DeepCopy() *ReservedIPAssociationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPAssociationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPClaim) DeepCopyInto(out *ReservedIPClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClaim.
+func (in *ReservedIPClaim) DeepCopy() *ReservedIPClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPClaimList) DeepCopyInto(out *ReservedIPClaimList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ReservedIP, len(*in))
+		*out = make([]ReservedIPClaim, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPList.
-func (in *ReservedIPList) DeepCopy() *ReservedIPList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClaimList.
+func (in *ReservedIPClaimList) DeepCopy() *ReservedIPClaimList {
 	if in == nil {
 		return nil
 	}
-	out := new(ReservedIPList)
+	out := new(ReservedIPClaimList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ReservedIPList) DeepCopyObject() runtime.Object {
+func (in *ReservedIPClaimList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -185,46 +438,547 @@ func (in *ReservedIPList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReservedIPSpec) DeepCopyInto(out *ReservedIPSpec) {
+func (in *ReservedIPClaimSpec) DeepCopyInto(out *ReservedIPClaimSpec) {
 	*out = *in
-	if in.Assignment != nil {
-		in, out := &in.Assignment, &out.Assignment
-		*out = new(ReservedIPAssignment)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClaimSpec.
+func (in *ReservedIPClaimSpec) DeepCopy() *ReservedIPClaimSpec {
+	if in == nil {
+		return nil
 	}
-	if in.Tags != nil {
-		in, out := &in.Tags, &out.Tags
-		*out = new(map[string]string)
-		if **in != nil {
-			in, out := *in, *out
-			*out = make(map[string]string, len(*in))
-			for key, val := range *in {
-				(*out)[key] = val
-			}
+	out := new(ReservedIPClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPClaimStatus) DeepCopyInto(out *ReservedIPClaimStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClaimStatus.
+func (in *ReservedIPClaimStatus) DeepCopy() *ReservedIPClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPClass) DeepCopyInto(out *ReservedIPClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClass.
+func (in *ReservedIPClass) DeepCopy() *ReservedIPClass {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPClassList) DeepCopyInto(out *ReservedIPClassList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReservedIPClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPSpec.
-func (in *ReservedIPSpec) DeepCopy() *ReservedIPSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClassList.
+func (in *ReservedIPClassList) DeepCopy() *ReservedIPClassList {
 	if in == nil {
 		return nil
 	}
-	out := new(ReservedIPSpec)
+	out := new(ReservedIPClassList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReservedIPStatus) DeepCopyInto(out *ReservedIPStatus) {
+func (in *ReservedIPClassSpec) DeepCopyInto(out *ReservedIPClassSpec) {
 	*out = *in
-	if in.Assignment != nil {
-		in, out := &in.Assignment, &out.Assignment
-		*out = new(ReservedIPAssignment)
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPClassSpec.
+func (in *ReservedIPClassSpec) DeepCopy() *ReservedIPClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPFailover) DeepCopyInto(out *ReservedIPFailover) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPFailover.
+func (in *ReservedIPFailover) DeepCopy() *ReservedIPFailover {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPFailover)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPFailover) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPFailoverList) DeepCopyInto(out *ReservedIPFailoverList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReservedIPFailover, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPFailoverList.
+func (in *ReservedIPFailoverList) DeepCopy() *ReservedIPFailoverList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPFailoverList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPFailoverList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPFailoverSpec) DeepCopyInto(out *ReservedIPFailoverSpec) {
+	*out = *in
+	in.PodSelector.DeepCopyInto(&out.PodSelector)
+	if in.Priority != nil {
+		in, out := &in.Priority, &out.Priority
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPFailoverSpec.
+func (in *ReservedIPFailoverSpec) DeepCopy() *ReservedIPFailoverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPFailoverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPFailoverStatus) DeepCopyInto(out *ReservedIPFailoverStatus) {
+	*out = *in
+	if in.LastFailoverTime != nil {
+		in, out := &in.LastFailoverTime, &out.LastFailoverTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPFailoverStatus.
+func (in *ReservedIPFailoverStatus) DeepCopy() *ReservedIPFailoverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPFailoverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPList) DeepCopyInto(out *ReservedIPList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReservedIP, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPList.
+func (in *ReservedIPList) DeepCopy() *ReservedIPList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPPolicy) DeepCopyInto(out *ReservedIPPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPPolicy.
+func (in *ReservedIPPolicy) DeepCopy() *ReservedIPPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPPolicyList) DeepCopyInto(out *ReservedIPPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReservedIPPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPPolicyList.
+func (in *ReservedIPPolicyList) DeepCopy() *ReservedIPPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPPolicySpec) DeepCopyInto(out *ReservedIPPolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedAssignmentTypes != nil {
+		in, out := &in.AllowedAssignmentTypes, &out.AllowedAssignmentTypes
+		*out = make([]ReservedIPAssignmentType, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedPrivateIPCIDRs != nil {
+		in, out := &in.AllowedPrivateIPCIDRs, &out.AllowedPrivateIPCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedPublicIPPoolIDs != nil {
+		in, out := &in.AllowedPublicIPPoolIDs, &out.AllowedPublicIPPoolIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedCompartmentIDs != nil {
+		in, out := &in.AllowedCompartmentIDs, &out.AllowedCompartmentIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedAccountNames != nil {
+		in, out := &in.AllowedAccountNames, &out.AllowedAccountNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowTakeover != nil {
+		in, out := &in.AllowTakeover, &out.AllowTakeover
+		*out = new(bool)
 		**out = **in
 	}
 }
 
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPPolicySpec.
+func (in *ReservedIPPolicySpec) DeepCopy() *ReservedIPPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPQuota) DeepCopyInto(out *ReservedIPQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPQuota.
+func (in *ReservedIPQuota) DeepCopy() *ReservedIPQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPQuotaLimits) DeepCopyInto(out *ReservedIPQuotaLimits) {
+	*out = *in
+	if in.ReservedIPs != nil {
+		in, out := &in.ReservedIPs, &out.ReservedIPs
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AssignedReservedIPs != nil {
+		in, out := &in.AssignedReservedIPs, &out.AssignedReservedIPs
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPQuotaLimits.
+func (in *ReservedIPQuotaLimits) DeepCopy() *ReservedIPQuotaLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPQuotaLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPQuotaList) DeepCopyInto(out *ReservedIPQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReservedIPQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPQuotaList.
+func (in *ReservedIPQuotaList) DeepCopy() *ReservedIPQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReservedIPQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPQuotaSpec) DeepCopyInto(out *ReservedIPQuotaSpec) {
+	*out = *in
+	in.Hard.DeepCopyInto(&out.Hard)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPQuotaSpec.
+func (in *ReservedIPQuotaSpec) DeepCopy() *ReservedIPQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPQuotaStatus) DeepCopyInto(out *ReservedIPQuotaStatus) {
+	*out = *in
+	out.Used = in.Used
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPQuotaStatus.
+func (in *ReservedIPQuotaStatus) DeepCopy() *ReservedIPQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPQuotaUsage) DeepCopyInto(out *ReservedIPQuotaUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPQuotaUsage.
+func (in *ReservedIPQuotaUsage) DeepCopy() *ReservedIPQuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPSpec) DeepCopyInto(out *ReservedIPSpec) {
+	*out = *in
+	if in.Assignment != nil {
+		in, out := &in.Assignment, &out.Assignment
+		*out = new(ReservedIPAssignment)
+		**out = **in
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(map[string]string)
+		if **in != nil {
+			in, out := *in, *out
+			*out = make(map[string]string, len(*in))
+			for key, val := range *in {
+				(*out)[key] = val
+			}
+		}
+	}
+	if in.Evacuation != nil {
+		in, out := &in.Evacuation, &out.Evacuation
+		*out = new(Evacuation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPSpec.
+func (in *ReservedIPSpec) DeepCopy() *ReservedIPSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedIPSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedIPStatus) DeepCopyInto(out *ReservedIPStatus) {
+	*out = *in
+	if in.Assignment != nil {
+		in, out := &in.Assignment, &out.Assignment
+		*out = new(ReservedIPAssignment)
+		**out = **in
+	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedIPStatus.
 func (in *ReservedIPStatus) DeepCopy() *ReservedIPStatus {
 	if in == nil {
@@ -0,0 +1,76 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// ClusterReservedIPReconciler reconciles a ClusterReservedIP object. It shares
+// the state machine of the ReservedIPReconciler.
+type ClusterReservedIPReconciler struct {
+	ReservedIPReconciler
+}
+
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=clusterreservedips,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=clusterreservedips/status,verbs=get;update;patch
+
+func (r *ClusterReservedIPReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("clusterReservedIP", req.Name)
+
+	var reservedIP ociv1alpha1.ClusterReservedIP
+	if err := r.Get(ctx, req.NamespacedName, &reservedIP); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	res, err := r.handleRequest(ctx, &reservedIP, log)
+	if err != nil {
+		r.Recorder.Event(&reservedIP, "Warning", "ReconcileError", err.Error())
+	}
+	return res, err
+}
+
+func (r *ClusterReservedIPReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ClusterReservedIP{}, leaseField, leaseIndexKey); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ClusterReservedIP{}, assignmentTargetField, assignmentTargetIndexKey); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ClusterReservedIP{}, accountNameField, accountNameIndexKey); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&ociv1alpha1.ClusterReservedIP{}).
+		Watches(&source.Kind{Type: &coordinationv1.Lease{}}, enqueueForLease(mgr.GetClient(), &ociv1alpha1.ClusterReservedIPList{}), builder.WithPredicates(leasePredicate())).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIP{}}, enqueueConflicting(mgr.GetClient(), &ociv1alpha1.ClusterReservedIPList{})).
+		Watches(&source.Kind{Type: &ociv1alpha1.ClusterReservedIP{}}, enqueueConflicting(mgr.GetClient(), &ociv1alpha1.ClusterReservedIPList{}))
+	if r.Accounts != nil {
+		b = b.Watches(&source.Kind{Type: &ociv1alpha1.OCIAccount{}}, r.Accounts.enqueueForAccount(mgr.GetClient(), &ociv1alpha1.ClusterReservedIPList{})).
+			Watches(source.NewKindWithCache(&corev1.Secret{}, r.Accounts.Secrets), r.Accounts.enqueueForSecret(mgr.GetClient(), &ociv1alpha1.ClusterReservedIPList{}))
+	}
+	return b.Complete(r)
+}
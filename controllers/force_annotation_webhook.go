@@ -0,0 +1,106 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+const (
+	forceAnnotationWebhookPath = "/validate-oci-k8s-logmein-com-v1alpha1-force-annotations"
+
+	// ForceReleaseVerb is the verb on reservedips or clusterreservedips
+	// needed to set the force-release annotation
+	ForceReleaseVerb = "force-release"
+	// ForceAbandonVerb is the verb on reservedipassociations needed to set
+	// the force-abandon annotation
+	ForceAbandonVerb = "force-abandon"
+)
+
+// ForceAnnotationValidator rejects setting the force-release and
+// force-abandon annotations by users who aren't allowed the dedicated verb on
+// the resource, as they leave public IPs behind in OCI
+type ForceAnnotationValidator struct {
+	Client client.Client
+}
+
+// +kubebuilder:webhook:path=/validate-oci-k8s-logmein-com-v1alpha1-force-annotations,mutating=false,failurePolicy=fail,sideEffects=None,groups=oci.k8s.logmein.com,resources=reservedips;clusterreservedips;reservedipassociations,verbs=create;update,versions=v1alpha1,name=vforceannotations.oci.k8s.logmein.com,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+func (v *ForceAnnotationValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	annotation, verb := ociv1alpha1.ForceReleaseAnnotation, ForceReleaseVerb
+	if req.Kind.Kind == "ReservedIPAssociation" {
+		annotation, verb = ociv1alpha1.ForceAbandonAnnotation, ForceAbandonVerb
+	}
+
+	var obj, old metav1.PartialObjectMetadata
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(req.OldObject.Raw) > 0 {
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if obj.Annotations[annotation] != "true" || old.Annotations[annotation] == "true" {
+		return admission.Allowed("")
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: req.Namespace,
+				Verb:      verb,
+				Group:     req.Resource.Group,
+				Version:   req.Resource.Version,
+				Resource:  req.Resource.Resource,
+				Name:      req.Name,
+			},
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if !review.Status.Allowed {
+		return admission.Denied(fmt.Sprintf("setting %s needs the %s verb on %s", annotation, verb, req.Resource.Resource))
+	}
+	return admission.Allowed("")
+}
+
+func (v *ForceAnnotationValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(forceAnnotationWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// authorizer answers SubjectAccessReviews by granting the verbs in allowed to
+// all users and records the reviews
+type authorizer struct {
+	client.Client
+	allowed map[string]bool
+	reviews []authorizationv1.SubjectAccessReviewSpec
+}
+
+func (a *authorizer) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return a.Client.Create(ctx, obj, opts...)
+	}
+	a.reviews = append(a.reviews, review.Spec)
+	review.Status.Allowed = a.allowed[review.Spec.ResourceAttributes.Verb]
+	return nil
+}
+
+func TestForceAnnotationValidator(t *testing.T) {
+	raw := func(annotations map[string]string) runtime.RawExtension {
+		data, err := json.Marshal(metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "a", Annotations: annotations}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	forceRelease := map[string]string{ociv1alpha1.ForceReleaseAnnotation: "true"}
+	forceAbandon := map[string]string{ociv1alpha1.ForceAbandonAnnotation: "true"}
+
+	tests := []struct {
+		name       string
+		kind       string
+		old        map[string]string
+		obj        map[string]string
+		allowed    map[string]bool
+		want       bool
+		wantReview string
+	}{
+		{
+			name: "without annotation",
+			kind: "ReservedIP",
+			want: true,
+		},
+		{
+			name:       "force release with the verb",
+			kind:       "ReservedIP",
+			obj:        forceRelease,
+			allowed:    map[string]bool{ForceReleaseVerb: true},
+			want:       true,
+			wantReview: ForceReleaseVerb,
+		},
+		{
+			name:       "force release without the verb",
+			kind:       "ReservedIP",
+			obj:        forceRelease,
+			allowed:    map[string]bool{ForceAbandonVerb: true},
+			wantReview: ForceReleaseVerb,
+		},
+		{
+			name: "annotation set before",
+			kind: "ReservedIP",
+			old:  forceRelease,
+			obj:  forceRelease,
+			want: true,
+		},
+		{
+			name:       "force abandon needs its own verb",
+			kind:       "ReservedIPAssociation",
+			obj:        forceAbandon,
+			allowed:    map[string]bool{ForceReleaseVerb: true},
+			wantReview: ForceAbandonVerb,
+		},
+		{
+			name:       "force abandon with the verb",
+			kind:       "ReservedIPAssociation",
+			obj:        forceAbandon,
+			allowed:    map[string]bool{ForceAbandonVerb: true},
+			want:       true,
+			wantReview: ForceAbandonVerb,
+		},
+		{
+			name: "force release on an association is ignored",
+			kind: "ReservedIPAssociation",
+			obj:  forceRelease,
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &authorizer{Client: fake.NewClientBuilder().Build(), allowed: tt.allowed}
+			v := &ForceAnnotationValidator{Client: a}
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Group: ociv1alpha1.GroupVersion.Group, Version: "v1alpha1", Kind: tt.kind},
+				Resource:  metav1.GroupVersionResource{Group: ociv1alpha1.GroupVersion.Group, Version: "v1alpha1", Resource: "reservedips"},
+				Name:      "a",
+				Namespace: "default",
+				UserInfo:  authenticationv1.UserInfo{Username: "jane", Groups: []string{"developers"}},
+				Object:    raw(tt.obj),
+			}}
+			if tt.old != nil {
+				req.Operation = admissionv1.Update
+				req.OldObject = raw(tt.old)
+			}
+
+			resp := v.Handle(context.Background(), req)
+			if resp.Allowed != tt.want {
+				t.Errorf("Handle() allowed = %v, want %v: %v", resp.Allowed, tt.want, resp.Result)
+			}
+			if tt.wantReview == "" {
+				if len(a.reviews) > 0 {
+					t.Errorf("unexpected SubjectAccessReviews %+v", a.reviews)
+				}
+				return
+			}
+			if len(a.reviews) != 1 {
+				t.Fatalf("got %d SubjectAccessReviews, want 1", len(a.reviews))
+			}
+			review := a.reviews[0]
+			if review.User != "jane" || review.ResourceAttributes.Verb != tt.wantReview || review.ResourceAttributes.Namespace != "default" || review.ResourceAttributes.Name != "a" {
+				t.Errorf("unexpected SubjectAccessReview %+v %+v", review, review.ResourceAttributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_oci_operator_cache_requests_total",
+		Help: "Number of lookups in the VCN caches by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheRequests)
+}
+
+const (
+	// privateIPTTL is how long the OCID of a private IP is cached. Pod IPs
+	// are recycled, and the private IP object of a reused IP may differ.
+	privateIPTTL = 10 * time.Minute
+	// maxPrivateIPs bounds the number of cached private IPs; the oldest
+	// entry is evicted first.
+	maxPrivateIPs = 4096
+)
+
+// NetworkCache caches the subnets of the VCN and the OCIDs of private IPs, so
+// assignments don't need to list the whole VCN every time. It is safe for
+// concurrent use and shared by all reconcilers talking to the same VCN.
+type NetworkCache struct {
+	// How long the list of subnets is used before it is fetched again
+	SubnetRefreshInterval time.Duration
+
+	mu               sync.Mutex
+	subnets          []cachedSubnet
+	subnetsFetchedAt time.Time
+	privateIPIDs     map[string]cachedPrivateIP
+}
+
+type cachedPrivateIP struct {
+	id        string
+	fetchedAt time.Time
+}
+
+type cachedSubnet struct {
+	id   string
+	cidr *net.IPNet
+}
+
+// getSubnets returns the cached subnets if they are younger than the refresh
+// interval.
+func (c *NetworkCache) getSubnets() ([]cachedSubnet, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.subnets == nil || time.Since(c.subnetsFetchedAt) > c.SubnetRefreshInterval {
+		cacheRequests.WithLabelValues("subnets", "miss").Inc()
+		return nil, false
+	}
+	cacheRequests.WithLabelValues("subnets", "hit").Inc()
+	return c.subnets, true
+}
+
+func (c *NetworkCache) setSubnets(subnets []cachedSubnet) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subnets = subnets
+	c.subnetsFetchedAt = time.Now()
+}
+
+// invalidateSubnets makes the next lookup fetch the subnets again, e.g. when
+// a private IP isn't found in the subnet that should contain it.
+func (c *NetworkCache) invalidateSubnets() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subnets = nil
+}
+
+func (c *NetworkCache) getPrivateIPID(privateIP string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.privateIPIDs[privateIP]
+	if ok && time.Since(entry.fetchedAt) > privateIPTTL {
+		delete(c.privateIPIDs, privateIP)
+		ok = false
+	}
+	if !ok {
+		cacheRequests.WithLabelValues("private_ip", "miss").Inc()
+		return "", false
+	}
+	cacheRequests.WithLabelValues("private_ip", "hit").Inc()
+	return entry.id, true
+}
+
+func (c *NetworkCache) setPrivateIPID(privateIP, id string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.privateIPIDs == nil {
+		c.privateIPIDs = map[string]cachedPrivateIP{}
+	}
+	if _, ok := c.privateIPIDs[privateIP]; !ok && len(c.privateIPIDs) >= maxPrivateIPs {
+		c.evictPrivateIP()
+	}
+	c.privateIPIDs[privateIP] = cachedPrivateIP{id: id, fetchedAt: time.Now()}
+}
+
+// evictPrivateIP removes expired private IPs, or the oldest one if none has
+// expired. c.mu must be held.
+func (c *NetworkCache) evictPrivateIP() {
+	var oldest string
+	var oldestFetchedAt time.Time
+	expired := false
+	for ip, entry := range c.privateIPIDs {
+		if time.Since(entry.fetchedAt) > privateIPTTL {
+			delete(c.privateIPIDs, ip)
+			expired = true
+		} else if oldest == "" || entry.fetchedAt.Before(oldestFetchedAt) {
+			oldest, oldestFetchedAt = ip, entry.fetchedAt
+		}
+	}
+	if !expired && oldest != "" {
+		delete(c.privateIPIDs, oldest)
+	}
+}
+
+// invalidatePrivateIP forgets the OCID of a private IP, e.g. because the pod
+// that had it is gone or OCI didn't find it, as the private IP object might
+// have been recreated.
+func (c *NetworkCache) invalidatePrivateIP(privateIP string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.privateIPIDs, privateIP)
+}
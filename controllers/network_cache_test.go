@@ -0,0 +1,153 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNetworkCacheSubnets(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("10.0.16.0/20")
+	subnets := []cachedSubnet{{id: "ocid1.subnet.oc1..a", cidr: cidr}}
+
+	tests := []struct {
+		name      string
+		fetchedAt time.Time
+		// invalidate after setting the subnets
+		invalidate bool
+		want       bool
+	}{
+		{name: "fresh", fetchedAt: time.Now(), want: true},
+		{name: "older than the refresh interval", fetchedAt: time.Now().Add(-2 * time.Minute)},
+		{name: "invalidated", fetchedAt: time.Now(), invalidate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &NetworkCache{SubnetRefreshInterval: time.Minute}
+			c.setSubnets(subnets)
+			c.subnetsFetchedAt = tt.fetchedAt
+			if tt.invalidate {
+				c.invalidateSubnets()
+			}
+			if _, ok := c.getSubnets(); ok != tt.want {
+				t.Errorf("getSubnets() found = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkCachePrivateIPTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		age    time.Duration
+		wantID string
+		want   bool
+	}{
+		{name: "fresh", age: time.Minute, wantID: "ocid1.privateip.oc1..a", want: true},
+		{name: "expired", age: privateIPTTL + time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &NetworkCache{}
+			c.setPrivateIPID("10.0.16.5", "ocid1.privateip.oc1..a")
+			c.privateIPIDs["10.0.16.5"] = cachedPrivateIP{id: "ocid1.privateip.oc1..a", fetchedAt: time.Now().Add(-tt.age)}
+
+			id, ok := c.getPrivateIPID("10.0.16.5")
+			if id != tt.wantID || ok != tt.want {
+				t.Errorf("getPrivateIPID() = %q, %v, want %q, %v", id, ok, tt.wantID, tt.want)
+			}
+			if _, cached := c.privateIPIDs["10.0.16.5"]; cached != tt.want {
+				t.Errorf("still cached = %v, want %v", cached, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		// number of entries that have expired, starting with the oldest
+		expired  int
+		wantSize int
+	}{
+		{name: "oldest entry is evicted", wantSize: maxPrivateIPs},
+		{name: "expired entries are evicted", expired: 10, wantSize: maxPrivateIPs - 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &NetworkCache{privateIPIDs: map[string]cachedPrivateIP{}}
+			now := time.Now()
+			for i := 0; i < maxPrivateIPs; i++ {
+				fetchedAt := now.Add(-time.Duration(maxPrivateIPs-i) * time.Millisecond)
+				if i < tt.expired {
+					fetchedAt = now.Add(-privateIPTTL - time.Duration(maxPrivateIPs-i)*time.Second)
+				}
+				c.privateIPIDs[fmt.Sprintf("ip-%d", i)] = cachedPrivateIP{id: fmt.Sprintf("id-%d", i), fetchedAt: fetchedAt}
+			}
+
+			c.setPrivateIPID("new", "id-new")
+
+			if len(c.privateIPIDs) != tt.wantSize {
+				t.Errorf("size = %d, want %d", len(c.privateIPIDs), tt.wantSize)
+			}
+			if _, ok := c.privateIPIDs["ip-0"]; ok {
+				t.Error("oldest entry wasn't evicted")
+			}
+			if _, ok := c.privateIPIDs[fmt.Sprintf("ip-%d", tt.expired+1)]; !ok {
+				t.Error("unexpired entry was evicted")
+			}
+			if _, ok := c.privateIPIDs["new"]; !ok {
+				t.Error("new entry wasn't added")
+			}
+		})
+	}
+}
+
+func TestNetworkCacheUpdateDoesNotEvict(t *testing.T) {
+	c := &NetworkCache{privateIPIDs: map[string]cachedPrivateIP{}}
+	for i := 0; i < maxPrivateIPs; i++ {
+		c.privateIPIDs[fmt.Sprintf("ip-%d", i)] = cachedPrivateIP{id: fmt.Sprintf("id-%d", i), fetchedAt: time.Now()}
+	}
+
+	c.setPrivateIPID("ip-0", "id-updated")
+
+	if len(c.privateIPIDs) != maxPrivateIPs {
+		t.Errorf("size = %d, want %d", len(c.privateIPIDs), maxPrivateIPs)
+	}
+	if id, _ := c.getPrivateIPID("ip-0"); id != "id-updated" {
+		t.Errorf("getPrivateIPID() = %q, want %q", id, "id-updated")
+	}
+}
+
+func TestNilNetworkCache(t *testing.T) {
+	var c *NetworkCache
+	c.setSubnets(nil)
+	c.setPrivateIPID("10.0.16.5", "ocid1.privateip.oc1..a")
+	c.invalidateSubnets()
+	c.invalidatePrivateIP("10.0.16.5")
+	if _, ok := c.getSubnets(); ok {
+		t.Error("nil cache returned subnets")
+	}
+	if _, ok := c.getPrivateIPID("10.0.16.5"); ok {
+		t.Error("nil cache returned a private IP")
+	}
+}
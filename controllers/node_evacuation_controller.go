@@ -0,0 +1,381 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+const (
+	nodeNameField        = "spec.nodeName"
+	assignedPodNameField = ".status.assignment.podName"
+	evacuatedNodeField   = ".evacuatedFromNode"
+)
+
+// evacuationRecord is the value of the EvacuatedAnnotation
+type evacuationRecord struct {
+	// Node the ReservedIP was first evacuated from
+	Node string `json:"node"`
+	// Assignment from before the evacuation, restored when Node recovers
+	Assignment *ociv1alpha1.ReservedIPAssignment `json:"assignment"`
+	// Evacuated is the assignment set by the evacuation. If spec.assignment
+	// was changed since, it isn't restored.
+	Evacuated *ociv1alpha1.ReservedIPAssignment `json:"evacuated,omitempty"`
+}
+
+// getEvacuationRecord returns the evacuation record of the ReservedIP, or nil
+// if it wasn't evacuated.
+func getEvacuationRecord(reservedIP client.Object) *evacuationRecord {
+	value, ok := reservedIP.GetAnnotations()[ociv1alpha1.EvacuatedAnnotation]
+	if !ok {
+		return nil
+	}
+	var record evacuationRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil
+	}
+	return &record
+}
+
+// NodeEvacuationReconciler watches Nodes and evacuates ReservedIPs assigned
+// to pods on nodes that are NotReady or cordoned, according to their
+// evacuation policy.
+//
+// ReservedIPs managed by a ReservedIPAssociation or ReservedIPFailover are left
+// alone, as their assignment is owned by the respective controller, and so are
+// ReservedIPs following a Lease, which move with the leader anyway. The
+// assignment of evacuated ReservedIPs is restored once the node recovers.
+type NodeEvacuationReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// Policy for ReservedIPs that don't define spec.evacuation.policy
+	DefaultPolicy ociv1alpha1.EvacuationPolicy
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+func (r *NodeEvacuationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("node", req.Name)
+
+	var node corev1.Node
+	if err := r.Get(ctx, req.NamespacedName, &node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if isNodeHealthy(&node) {
+		return ctrl.Result{}, r.restore(ctx, &node, log)
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingFields{nodeNameField: node.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, pod := range pods.Items {
+		key := pod.Namespace + "/" + pod.Name
+		for _, list := range []client.ObjectList{&ociv1alpha1.ReservedIPList{}, &ociv1alpha1.ClusterReservedIPList{}} {
+			if err := r.List(ctx, list, client.MatchingFields{assignedPodNameField: key}); err != nil {
+				return ctrl.Result{}, err
+			}
+			err := meta.EachListItem(list, func(item runtime.Object) error {
+				return r.evacuate(ctx, item.(reservedIPObject), &node, &pod, log)
+			})
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// evacuate applies the evacuation policy to a ReservedIP assigned to a pod on
+// an unhealthy node.
+func (r *NodeEvacuationReconciler) evacuate(ctx context.Context, reservedIP reservedIPObject, node *corev1.Node, pod *corev1.Pod, log logr.Logger) error {
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	annotations := reservedIP.GetAnnotations()
+	if annotations[ociv1alpha1.AssociationAnnotation] != "" || annotations[ociv1alpha1.FailoverAnnotation] != "" {
+		return nil
+	}
+	if status.State != "assigned" || spec.Assignment == nil || !status.Assignment.MatchesSpec(*spec.Assignment) {
+		// already moving
+		return nil
+	}
+	if spec.Assignment.LeaseName != "" {
+		// leader election moves it once the Lease expires
+		return nil
+	}
+
+	policy := r.DefaultPolicy
+	if spec.Evacuation != nil && spec.Evacuation.Policy != "" {
+		policy = spec.Evacuation.Policy
+	}
+	log = log.WithValues("reservedIP", client.ObjectKeyFromObject(reservedIP), "pod", pod.Name)
+
+	switch policy {
+	case ociv1alpha1.EvacuationUnassign:
+		log.Info("evacuating ReservedIP by unassigning it")
+		if err := r.setAssignment(ctx, reservedIP, node, nil); err != nil {
+			return err
+		}
+		r.Recorder.Event(reservedIP, "Warning", "Evacuated", fmt.Sprintf("Unassigned from pod %s because node %s is %s", pod.Name, node.Name, nodeProblem(node)))
+
+	case ociv1alpha1.EvacuationMoveToStandby:
+		standby, err := r.findStandbyPod(ctx, spec.Evacuation, pod)
+		if err != nil {
+			return err
+		}
+		if standby == "" {
+			r.Recorder.Event(reservedIP, "Warning", "EvacuationFailed", fmt.Sprintf("No Ready standby pod found to move to from pod %s on node %s", pod.Name, node.Name))
+			return nil
+		}
+
+		log.Info("evacuating ReservedIP to standby pod", "standbyPod", standby)
+		assignment := &ociv1alpha1.ReservedIPAssignment{PodName: standby}
+		if reservedIP.GetNamespace() == "" {
+			assignment.Namespace = pod.Namespace
+		}
+		if err := r.setAssignment(ctx, reservedIP, node, assignment); err != nil {
+			return err
+		}
+		r.Recorder.Event(reservedIP, "Warning", "Evacuated", fmt.Sprintf("Moved from pod %s to standby pod %s because node %s is %s", pod.Name, standby, node.Name, nodeProblem(node)))
+	}
+
+	return nil
+}
+
+// setAssignment replaces the assignment of the ReservedIP for an evacuation
+// from node, recording the previous assignment in the EvacuatedAnnotation. If
+// the ReservedIP is evacuated again, e.g. from the standby pod, the first
+// record is kept.
+func (r *NodeEvacuationReconciler) setAssignment(ctx context.Context, reservedIP reservedIPObject, node *corev1.Node, assignment *ociv1alpha1.ReservedIPAssignment) error {
+	spec := reservedIP.GetSpec()
+	record := getEvacuationRecord(reservedIP)
+	if record == nil {
+		record = &evacuationRecord{Node: node.Name, Assignment: spec.Assignment.DeepCopy()}
+	}
+	record.Evacuated = assignment
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	patch := lockedMergeFrom(reservedIP)
+	spec.Assignment = assignment
+	annotations := reservedIP.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ociv1alpha1.EvacuatedAnnotation] = string(value)
+	reservedIP.SetAnnotations(annotations)
+	return r.Patch(ctx, reservedIP, patch, fieldOwner)
+}
+
+// restore gives ReservedIPs evacuated from the now healthy node their previous
+// assignment back, unless it was changed since or the pod is gone.
+func (r *NodeEvacuationReconciler) restore(ctx context.Context, node *corev1.Node, log logr.Logger) error {
+	for _, list := range []client.ObjectList{&ociv1alpha1.ReservedIPList{}, &ociv1alpha1.ClusterReservedIPList{}} {
+		if err := r.List(ctx, list, client.MatchingFields{evacuatedNodeField: node.Name}); err != nil {
+			return err
+		}
+		err := meta.EachListItem(list, func(item runtime.Object) error {
+			reservedIP := item.(reservedIPObject)
+			record := getEvacuationRecord(reservedIP)
+			if record == nil {
+				return nil
+			}
+			log := log.WithValues("reservedIP", client.ObjectKeyFromObject(reservedIP))
+			spec := reservedIP.GetSpec()
+
+			patch := lockedMergeFrom(reservedIP)
+			annotations := reservedIP.GetAnnotations()
+			delete(annotations, ociv1alpha1.EvacuatedAnnotation)
+			reservedIP.SetAnnotations(annotations)
+			var message string
+			switch {
+			case !reflect.DeepEqual(spec.Assignment, record.Evacuated):
+				log.Info("assignment changed since the evacuation; not restoring it")
+			case record.Assignment == nil:
+				// nothing to restore
+			default:
+				gone, err := r.podGone(ctx, reservedIP, record.Assignment)
+				if err != nil {
+					return err
+				}
+				if gone {
+					message = fmt.Sprintf("Not restoring the assignment to pod %s because the pod is gone", record.Assignment.PodName)
+					break
+				}
+				log.Info("node recovered; restoring assignment")
+				spec.Assignment = record.Assignment
+				message = fmt.Sprintf("Restored the assignment from before the evacuation because node %s recovered", node.Name)
+			}
+			if err := r.Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+				return err
+			}
+			if message != "" {
+				r.Recorder.Event(reservedIP, "Normal", "EvacuationRestored", message)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// podGone reports whether the pod of the assignment of the ReservedIP doesn't
+// exist anymore or is being deleted.
+func (r *NodeEvacuationReconciler) podGone(ctx context.Context, reservedIP reservedIPObject, assignment *ociv1alpha1.ReservedIPAssignment) (bool, error) {
+	if assignment.PodName == "" {
+		return false, nil
+	}
+	namespace := reservedIP.GetNamespace()
+	if namespace == "" {
+		namespace = assignment.Namespace
+	}
+	var pod corev1.Pod
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: assignment.PodName}, &pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return !pod.DeletionTimestamp.IsZero(), nil
+}
+
+// findStandbyPod returns the oldest Ready pod matching the standby selector
+// that runs on a healthy node, or an empty string if there is none.
+func (r *NodeEvacuationReconciler) findStandbyPod(ctx context.Context, evacuation *ociv1alpha1.Evacuation, evacuated *corev1.Pod) (string, error) {
+	if evacuation == nil || evacuation.StandbyPodSelector == nil {
+		return "", nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(evacuation.StandbyPodSelector)
+	if err != nil {
+		return "", err
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(evacuated.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "", err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+
+	for _, pod := range pods.Items {
+		if pod.Name == evacuated.Name || pod.Spec.NodeName == evacuated.Spec.NodeName || !pod.DeletionTimestamp.IsZero() || !isPodReady(&pod) {
+			continue
+		}
+		var node corev1.Node
+		if err := r.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, &node); err != nil {
+			return "", client.IgnoreNotFound(err)
+		}
+		if isNodeHealthy(&node) {
+			return pod.Name, nil
+		}
+	}
+	return "", nil
+}
+
+func isNodeHealthy(node *corev1.Node) bool {
+	return nodeProblem(node) == ""
+}
+
+// nodeProblem describes why a node should be evacuated, or returns an empty
+// string if it's healthy.
+func nodeProblem(node *corev1.Node) string {
+	if node.Spec.Unschedulable {
+		return "cordoned"
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue {
+			return "NotReady"
+		}
+	}
+	return ""
+}
+
+// assignedPodIndexKey returns the namespace/name of the pod the ReservedIP is
+// currently assigned to, for indexing under assignedPodNameField.
+func assignedPodIndexKey(obj client.Object) []string {
+	reservedIP := obj.(reservedIPObject)
+	assignment := reservedIP.GetStatus().Assignment
+	if assignment == nil || assignment.PodName == "" {
+		return nil
+	}
+	namespace := reservedIP.GetNamespace()
+	if namespace == "" {
+		namespace = assignment.Namespace
+	}
+	return []string{namespace + "/" + assignment.PodName}
+}
+
+// evacuatedNodeIndexKey returns the node the ReservedIP was evacuated from,
+// for indexing under evacuatedNodeField.
+func evacuatedNodeIndexKey(obj client.Object) []string {
+	record := getEvacuationRecord(obj)
+	if record == nil {
+		return nil
+	}
+	return []string{record.Node}
+}
+
+func (r *NodeEvacuationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &corev1.Pod{}, nodeNameField, func(obj client.Object) []string {
+		return []string{obj.(*corev1.Pod).Spec.NodeName}
+	}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &ociv1alpha1.ReservedIP{}, assignedPodNameField, assignedPodIndexKey); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &ociv1alpha1.ClusterReservedIP{}, assignedPodNameField, assignedPodIndexKey); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &ociv1alpha1.ReservedIP{}, evacuatedNodeField, evacuatedNodeIndexKey); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &ociv1alpha1.ClusterReservedIP{}, evacuatedNodeField, evacuatedNodeIndexKey); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("nodeevacuation").
+		For(&corev1.Node{}).
+		Complete(r)
+}
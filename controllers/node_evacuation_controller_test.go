@@ -0,0 +1,192 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestNodeProblem(t *testing.T) {
+	node := func(unschedulable bool, ready corev1.ConditionStatus) *corev1.Node {
+		n := &corev1.Node{Spec: corev1.NodeSpec{Unschedulable: unschedulable}}
+		if ready != "" {
+			n.Status.Conditions = []corev1.NodeCondition{
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+				{Type: corev1.NodeReady, Status: ready},
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		name string
+		node *corev1.Node
+		want string
+	}{
+		{name: "Ready", node: node(false, corev1.ConditionTrue)},
+		{name: "without conditions", node: node(false, "")},
+		{name: "NotReady", node: node(false, corev1.ConditionFalse), want: "NotReady"},
+		{name: "unknown", node: node(false, corev1.ConditionUnknown), want: "NotReady"},
+		{name: "cordoned", node: node(true, corev1.ConditionTrue), want: "cordoned"},
+		{name: "cordoned and NotReady", node: node(true, corev1.ConditionFalse), want: "cordoned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeProblem(tt.node); got != tt.want {
+				t.Errorf("nodeProblem() = %q, want %q", got, tt.want)
+			}
+			if got := isNodeHealthy(tt.node); got != (tt.want == "") {
+				t.Errorf("isNodeHealthy() = %v, want %v", got, tt.want == "")
+			}
+		})
+	}
+}
+
+func TestGetEvacuationRecord(t *testing.T) {
+	withAnnotation := func(value string) client.Object {
+		reservedIP := testReservedIP("a", nil)
+		reservedIP.Annotations = map[string]string{ociv1alpha1.EvacuatedAnnotation: value}
+		return reservedIP
+	}
+
+	tests := []struct {
+		name string
+		obj  client.Object
+		want *evacuationRecord
+	}{
+		{
+			name: "not evacuated",
+			obj:  testReservedIP("a", nil),
+		},
+		{
+			name: "malformed",
+			obj:  withAnnotation("node-a"),
+		},
+		{
+			name: "evacuated",
+			obj:  withAnnotation(`{"node":"node-a","assignment":{"podName":"web-0"},"evacuated":{"podName":"web-1"}}`),
+			want: &evacuationRecord{
+				Node:       "node-a",
+				Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "web-0"},
+				Evacuated:  &ociv1alpha1.ReservedIPAssignment{PodName: "web-1"},
+			},
+		},
+		{
+			name: "unassigned by the evacuation",
+			obj:  withAnnotation(`{"node":"node-a","assignment":{"podName":"web-0"}}`),
+			want: &evacuationRecord{Node: "node-a", Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "web-0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getEvacuationRecord(tt.obj)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEvacuationRecord() = %+v, want %+v", got, tt.want)
+			}
+			var wantKey []string
+			if tt.want != nil {
+				wantKey = []string{tt.want.Node}
+			}
+			if key := evacuatedNodeIndexKey(tt.obj); !reflect.DeepEqual(key, wantKey) {
+				t.Errorf("evacuatedNodeIndexKey() = %v, want %v", key, wantKey)
+			}
+		})
+	}
+}
+
+func TestSetAssignmentKeepsFirstRecord(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	original := &ociv1alpha1.ReservedIPAssignment{PodName: "web-0"}
+	reservedIP := testReservedIP("a", original.DeepCopy())
+	r := &NodeEvacuationReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(reservedIP).Build()}
+	ctx := context.Background()
+
+	// evacuated to a standby pod, whose node fails as well
+	standby := &ociv1alpha1.ReservedIPAssignment{PodName: "web-1"}
+	if err := r.setAssignment(ctx, reservedIP, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}, standby); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.setAssignment(ctx, reservedIP, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var got ociv1alpha1.ReservedIP
+	if err := r.Get(ctx, client.ObjectKeyFromObject(reservedIP), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Spec.Assignment != nil {
+		t.Errorf("spec.assignment = %+v, want nil", got.Spec.Assignment)
+	}
+	want := &evacuationRecord{Node: "node-a", Assignment: original}
+	if record := getEvacuationRecord(&got); !reflect.DeepEqual(record, want) {
+		t.Errorf("evacuation record = %+v, want %+v", record, want)
+	}
+}
+
+func TestAssignedPodIndexKey(t *testing.T) {
+	assigned := func(obj reservedIPObject, assignment *ociv1alpha1.ReservedIPAssignment) client.Object {
+		obj.GetStatus().Assignment = assignment
+		return obj
+	}
+
+	tests := []struct {
+		name string
+		obj  client.Object
+		want []string
+	}{
+		{
+			name: "unassigned",
+			obj:  testReservedIP("a", nil),
+		},
+		{
+			name: "assigned to a private IP",
+			obj:  assigned(testReservedIP("a", nil), &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.0.5"}),
+		},
+		{
+			name: "assigned to a pod",
+			obj:  assigned(testReservedIP("a", nil), &ociv1alpha1.ReservedIPAssignment{PodName: "web-0"}),
+			want: []string{"default/web-0"},
+		},
+		{
+			name: "ClusterReservedIP assigned to a pod",
+			obj:  assigned(&ociv1alpha1.ClusterReservedIP{}, &ociv1alpha1.ReservedIPAssignment{PodName: "web-0", Namespace: "web"}),
+			want: []string{"web/web-0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignedPodIndexKey(tt.obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignedPodIndexKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+	ociauth "github.com/oracle/oci-go-sdk/v31/common/auth"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+	"github.com/logmein/k8s-oci-operator/pkg/oci"
+)
+
+const accountNameField = ".spec.accountName"
+
+// OCIAccountClients builds OCI clients for OCIAccounts and caches them until
+// the account or its secret changes.
+type OCIAccountClients struct {
+	// Cache of the secrets in SecretNamespace, so the operator neither
+	// needs to cache nor to read all secrets of the cluster.
+	Secrets cache.Cache
+	// The only namespace the secrets of accounts may be in
+	SecretNamespace string
+
+	SubnetCacheRefreshInterval time.Duration
+
+	// Proxy, CA bundle and timeout for the clients. The endpoint isn't used, as
+	// accounts may be in other regions.
+	ClientOptions oci.ClientOptions
+
+	mu      sync.Mutex
+	clients map[string]*ociAccountClient
+}
+
+type ociAccountClient struct {
+	// generation of the account and resource version of its secret the
+	// client was built from
+	generation    int64
+	secretVersion string
+	vnc           *ocicore.VirtualNetworkClient
+	networkCache  *NetworkCache
+}
+
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=ociaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=get;list;watch
+
+// get returns the client for account, building a new one if the account or
+// its secret changed since the last call. The network cache is kept if only
+// the secret changed, e.g. because the credentials were rotated.
+func (c *OCIAccountClients) get(ctx context.Context, account *ociv1alpha1.OCIAccount) (*ociAccountClient, error) {
+	var secret *corev1.Secret
+	if ref := account.Spec.SecretRef; ref != nil {
+		if ref.Namespace != "" && ref.Namespace != c.SecretNamespace {
+			return nil, fmt.Errorf("secret of OCIAccount %s must be in namespace %s", account.Name, c.SecretNamespace)
+		}
+		secret = &corev1.Secret{}
+		key := client.ObjectKey{Namespace: c.SecretNamespace, Name: ref.Name}
+		if err := c.Secrets.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("cannot get secret of OCIAccount %s: %w", account.Name, err)
+		}
+	}
+
+	secretVersion := ""
+	if secret != nil {
+		secretVersion = secret.ResourceVersion
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.clients[account.Name]
+	if ok && cached.generation == account.Generation && cached.secretVersion == secretVersion {
+		return cached, nil
+	}
+
+	ocicfg, err := accountConfigurationProvider(account, secret)
+	if err != nil {
+		return nil, err
+	}
+	vnc, err := oci.NewVirtualNetworkClient(ocicfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OCI client for OCIAccount %s: %w", account.Name, err)
+	}
+	vnc.UserAgent = "k8s-oci-operator"
+	vnc.SetRegion(account.Spec.Region)
+	if err := c.ClientOptions.Configure(&vnc, false); err != nil {
+		return nil, err
+	}
+
+	networkCache := &NetworkCache{SubnetRefreshInterval: c.SubnetCacheRefreshInterval}
+	if ok && cached.generation == account.Generation {
+		// same region and VCN
+		networkCache = cached.networkCache
+	}
+
+	if c.clients == nil {
+		c.clients = map[string]*ociAccountClient{}
+	}
+	c.clients[account.Name] = &ociAccountClient{
+		generation:    account.Generation,
+		secretVersion: secretVersion,
+		vnc:           &vnc,
+		networkCache:  networkCache,
+	}
+	return c.clients[account.Name], nil
+}
+
+// forget drops the client of a deleted account
+func (c *OCIAccountClients) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.clients, name)
+}
+
+// getAccount returns the OCIAccount a ReservedIP in namespace references by
+// name. It fails if the account's namespaceSelector doesn't select the
+// namespace; ClusterReservedIPs, which have no namespace, may use all
+// accounts.
+func getAccount(ctx context.Context, c client.Reader, name, namespace string) (*ociv1alpha1.OCIAccount, error) {
+	var account ociv1alpha1.OCIAccount
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, &account); err != nil {
+		return nil, fmt.Errorf("cannot get OCIAccount %s: %w", name, err)
+	}
+	if namespace == "" {
+		return &account, nil
+	}
+	if account.Spec.NamespaceSelector == nil {
+		return nil, fmt.Errorf("OCIAccount %s may only be used by ClusterReservedIPs", name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(account.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespaceSelector of OCIAccount %s: %w", name, err)
+	}
+	var ns corev1.Namespace
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, &ns); err != nil {
+		return nil, err
+	}
+	if !selector.Matches(labels.Set(ns.Labels)) {
+		return nil, fmt.Errorf("OCIAccount %s may not be used in namespace %s", name, namespace)
+	}
+	return &account, nil
+}
+
+// checkAccount returns an error if a ReservedIP in namespace may not use the
+// OCIAccount it references. Accounts that don't exist yet are allowed; the
+// reconciler checks them once they do.
+func checkAccount(ctx context.Context, c client.Reader, namespace string, spec *ociv1alpha1.ReservedIPSpec) error {
+	if spec.AccountName == "" {
+		return nil
+	}
+	_, err := getAccount(ctx, c, spec.AccountName, namespace)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// accountNameIndexKey returns the OCIAccount the ReservedIP references, for
+// indexing under accountNameField.
+func accountNameIndexKey(obj client.Object) []string {
+	if name := obj.(reservedIPObject).GetSpec().AccountName; name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+// enqueueForAccount enqueues all objects of the given list type referencing
+// the changed OCIAccount, dropping its client if it was deleted.
+func (c *OCIAccountClients) enqueueForAccount(cl client.Client, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		if err := cl.Get(context.Background(), client.ObjectKeyFromObject(obj), &ociv1alpha1.OCIAccount{}); apierrors.IsNotFound(err) {
+			c.forget(obj.GetName())
+		}
+		return enqueueReferencing(cl, list, obj.GetName())
+	})
+}
+
+// enqueueForSecret enqueues all objects of the given list type referencing an
+// OCIAccount that uses the changed secret, so rotated keys are used at once.
+func (c *OCIAccountClients) enqueueForSecret(cl client.Client, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		var accounts ociv1alpha1.OCIAccountList
+		if err := cl.List(context.Background(), &accounts); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, account := range accounts.Items {
+			if ref := account.Spec.SecretRef; ref != nil && ref.Name == obj.GetName() {
+				requests = append(requests, enqueueReferencing(cl, list, account.Name)...)
+			}
+		}
+		return requests
+	})
+}
+
+func enqueueReferencing(c client.Client, list client.ObjectList, accountName string) []reconcile.Request {
+	list = list.DeepCopyObject().(client.ObjectList)
+	if err := c.List(context.Background(), list, client.MatchingFields{accountNameField: accountName}); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	_ = meta.EachListItem(list, func(item runtime.Object) error {
+		o := item.(client.Object)
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}})
+		return nil
+	})
+	return requests
+}
+
+func accountConfigurationProvider(account *ociv1alpha1.OCIAccount, secret *corev1.Secret) (ocicommon.ConfigurationProvider, error) {
+	switch account.Spec.AuthType {
+	case "", ociv1alpha1.OCIAccountAPIKey:
+		if secret == nil {
+			return nil, fmt.Errorf("OCIAccount %s needs a secretRef for the APIKey auth type", account.Name)
+		}
+		for _, key := range []string{ociv1alpha1.OCIAccountTenancyKey, ociv1alpha1.OCIAccountUserKey, ociv1alpha1.OCIAccountFingerprintKey, ociv1alpha1.OCIAccountPrivateKeyKey} {
+			if len(secret.Data[key]) == 0 {
+				return nil, fmt.Errorf("secret %s/%s of OCIAccount %s is missing key %s", secret.Namespace, secret.Name, account.Name, key)
+			}
+		}
+		var passphrase *string
+		if p, ok := secret.Data[ociv1alpha1.OCIAccountPassphraseKey]; ok {
+			passphrase = ocicommon.String(string(p))
+		}
+		return ocicommon.NewRawConfigurationProvider(
+			string(secret.Data[ociv1alpha1.OCIAccountTenancyKey]),
+			string(secret.Data[ociv1alpha1.OCIAccountUserKey]),
+			account.Spec.Region,
+			string(secret.Data[ociv1alpha1.OCIAccountFingerprintKey]),
+			string(secret.Data[ociv1alpha1.OCIAccountPrivateKeyKey]),
+			passphrase,
+		), nil
+	case ociv1alpha1.OCIAccountInstancePrincipal:
+		return ociauth.InstancePrincipalConfigurationProviderForRegion(ocicommon.StringToRegion(account.Spec.Region))
+	case ociv1alpha1.OCIAccountResourcePrincipal:
+		return ociauth.ResourcePrincipalConfigurationProvider()
+	default:
+		return nil, fmt.Errorf("OCIAccount %s has unknown auth type %s", account.Name, account.Spec.AuthType)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// secretCache serves secrets from a client instead of an informer cache
+type secretCache struct {
+	cache.Cache
+	reader client.Reader
+}
+
+func (c secretCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return c.reader.Get(ctx, key, obj)
+}
+
+// testAccountSecret returns a secret with an API key for an OCIAccount
+func testAccountSecret(t *testing.T) *corev1.Secret {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "oci-operator", Name: "tenancy-b"},
+		Data: map[string][]byte{
+			ociv1alpha1.OCIAccountTenancyKey:     []byte("ocid1.tenancy.oc1..b"),
+			ociv1alpha1.OCIAccountUserKey:        []byte("ocid1.user.oc1..b"),
+			ociv1alpha1.OCIAccountFingerprintKey: []byte("aa:bb"),
+			ociv1alpha1.OCIAccountPrivateKeyKey:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+	}
+}
+
+func TestAccountConfigurationProvider(t *testing.T) {
+	secret := testAccountSecret(t)
+	withoutKey := secret.DeepCopy()
+	delete(withoutKey.Data, ociv1alpha1.OCIAccountFingerprintKey)
+
+	tests := []struct {
+		name        string
+		authType    ociv1alpha1.OCIAccountAuthType
+		secret      *corev1.Secret
+		wantTenancy string
+		wantErr     bool
+	}{
+		{
+			name:        "API key by default",
+			secret:      secret,
+			wantTenancy: "ocid1.tenancy.oc1..b",
+		},
+		{
+			name:        "API key",
+			authType:    ociv1alpha1.OCIAccountAPIKey,
+			secret:      secret,
+			wantTenancy: "ocid1.tenancy.oc1..b",
+		},
+		{
+			name:    "API key without secret",
+			wantErr: true,
+		},
+		{
+			name:    "API key with missing key",
+			secret:  withoutKey,
+			wantErr: true,
+		},
+		{
+			name:     "unknown auth type",
+			authType: "Password",
+			secret:   secret,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &ociv1alpha1.OCIAccount{
+				ObjectMeta: metav1.ObjectMeta{Name: "b"},
+				Spec:       ociv1alpha1.OCIAccountSpec{Region: "eu-frankfurt-1", AuthType: tt.authType},
+			}
+			cfg, err := accountConfigurationProvider(account, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("accountConfigurationProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tenancy, err := cfg.TenancyOCID(); err != nil || tenancy != tt.wantTenancy {
+				t.Errorf("TenancyOCID() = %q, %v, want %q", tenancy, err, tt.wantTenancy)
+			}
+			if region, err := cfg.Region(); err != nil || region != "eu-frankfurt-1" {
+				t.Errorf("Region() = %q, %v, want %q", region, err, "eu-frankfurt-1")
+			}
+			if _, err := cfg.PrivateRSAKey(); err != nil {
+				t.Errorf("PrivateRSAKey() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	account := func(name string, selector *metav1.LabelSelector) *ociv1alpha1.OCIAccount {
+		return &ociv1alpha1.OCIAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       ociv1alpha1.OCIAccountSpec{NamespaceSelector: selector},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		account      string
+		namespace    string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{name: "ClusterReservedIPs may use any account", account: "cluster-only"},
+		{name: "selected namespace", account: "team-a", namespace: "team-a"},
+		{name: "other namespace", account: "team-a", namespace: "team-b", wantErr: true},
+		{name: "account without namespaceSelector", account: "cluster-only", namespace: "team-a", wantErr: true},
+		{name: "empty namespaceSelector selects all namespaces", account: "everyone", namespace: "team-b"},
+		{name: "invalid namespaceSelector", account: "invalid", namespace: "team-a", wantErr: true},
+		{name: "missing account", account: "missing", namespace: "team-a", wantErr: true, wantNotFound: true},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		account("cluster-only", nil),
+		account("team-a", &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}),
+		account("everyone", &metav1.LabelSelector{}),
+		account("invalid", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Is"}}}),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+	).Build()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getAccount(context.Background(), c, tt.account, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if apierrors.IsNotFound(err) != tt.wantNotFound {
+				t.Errorf("getAccount() error = %v, want not found %v", err, tt.wantNotFound)
+			}
+			if err == nil && got.Name != tt.account {
+				t.Errorf("getAccount() = %s, want %s", got.Name, tt.account)
+			}
+		})
+	}
+}
+
+func TestOCIAccountClientsRotation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := testAccountSecret(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+	clients := &OCIAccountClients{Secrets: secretCache{reader: c}, SecretNamespace: "oci-operator"}
+	account := &ociv1alpha1.OCIAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Generation: 1},
+		Spec: ociv1alpha1.OCIAccountSpec{
+			Region:    "eu-frankfurt-1",
+			SecretRef: &corev1.SecretReference{Name: "tenancy-b"},
+		},
+	}
+	ctx := context.Background()
+
+	first, err := clients.get(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := clients.get(ctx, account); err != nil || again != first {
+		t.Errorf("unchanged account got a new client")
+	}
+
+	// rotate the credentials
+	secret.Data[ociv1alpha1.OCIAccountFingerprintKey] = []byte("cc:dd")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := clients.get(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated == first || rotated.vnc == first.vnc {
+		t.Errorf("client wasn't rebuilt after the secret changed")
+	}
+	if rotated.networkCache != first.networkCache {
+		t.Errorf("network cache was dropped although only the secret changed")
+	}
+
+	// change the account, e.g. its VCN
+	account.Generation = 2
+	changed, err := clients.get(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == rotated || changed.networkCache == rotated.networkCache {
+		t.Errorf("client and network cache weren't rebuilt after the account changed")
+	}
+
+	clients.forget(account.Name)
+	if _, ok := clients.clients[account.Name]; ok {
+		t.Errorf("client of forgotten account is still cached")
+	}
+
+	account.Spec.SecretRef.Namespace = "default"
+	if _, err := clients.get(ctx, account); err == nil {
+		t.Errorf("secret outside the secret namespace was accepted")
+	}
+}
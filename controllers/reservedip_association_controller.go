@@ -18,20 +18,43 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"time"
 
 	"github.com/go-logr/logr"
 	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	reservedIPNameField = ".spec.reservedIPName"
+	podNameField        = ".spec.assignment.podName"
 )
 
 // ReservedIPReconciler reconciles a ReservedIP object
 type ReservedIPAssociationReconciler struct {
 	client.Client
-	Log logr.Logger
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	// AllowForceAbandon honors the force-abandon annotation
+	AllowForceAbandon bool
 }
 
 // +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipassociations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipassociations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 
 func (r *ReservedIPAssociationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("reservedIPAssociation", req.NamespacedName)
@@ -43,56 +66,449 @@ func (r *ReservedIPAssociationReconciler) Reconcile(ctx context.Context, req ctr
 
 	if reservedIPAssociation.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !containsString(reservedIPAssociation.ObjectMeta.Finalizers, finalizerName) {
-			reservedIPAssociation.ObjectMeta.Finalizers = append(reservedIPAssociation.ObjectMeta.Finalizers, finalizerName)
 			log.Info("New ReservedIP Association")
-			var reservedIP ociv1alpha1.ReservedIP
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: req.Namespace,
-				Name:      reservedIPAssociation.Spec.ReservedIPName,
-			}, &reservedIP); err != nil {
-				return ctrl.Result{}, err
-			}
-
-			if reservedIP.Spec.Assignment == nil && reservedIP.Status.State == "allocated" {
-				reservedIP.Spec.Assignment = reservedIPAssociation.Spec.Assignment
-			} else {
-				return ctrl.Result{}, fmt.Errorf("Cannot assign ReservedIP because it isn't in allocated state.")
-			}
+			return ctrl.Result{}, addFinalizer(ctx, r.Client, &reservedIPAssociation)
+		}
 
-			if err := r.Update(ctx, &reservedIP); err != nil {
+		deleted, requeueAfter, err := r.followPod(ctx, &reservedIPAssociation, log)
+		if err != nil || deleted {
+			return ctrl.Result{}, err
+		}
+		base := reservedIPAssociation.DeepCopy()
+		if err := r.bindReservedIP(ctx, &reservedIPAssociation, log); err != nil {
+			return ctrl.Result{}, err
+		}
+		if !reflect.DeepEqual(base.Status, reservedIPAssociation.Status) {
+			if err := r.Status().Patch(ctx, &reservedIPAssociation, client.MergeFrom(base), fieldOwner); err != nil {
 				return ctrl.Result{}, err
 			}
-
-			return ctrl.Result{}, r.Update(ctx, &reservedIPAssociation)
 		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	} else {
 		// Association is being deleted we want to unassign ReservedIP
 		if containsString(reservedIPAssociation.ObjectMeta.Finalizers, finalizerName) {
-			var reservedIP ociv1alpha1.ReservedIP
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: req.Namespace,
-				Name:      reservedIPAssociation.Spec.ReservedIPName,
-			}, &reservedIP); err != nil {
-				return ctrl.Result{}, err
+			forceAbandon := reservedIPAssociation.Annotations[ociv1alpha1.ForceAbandonAnnotation] == "true"
+			if forceAbandon && !r.AllowForceAbandon {
+				log.Info("Ignoring force abandon as it is disabled")
+				r.Recorder.Event(&reservedIPAssociation, "Warning", "ForceAbandonDisabled", "The force-abandon annotation is ignored as the operator was started without -enable-force-release")
+				forceAbandon = false
 			}
-
-			if reservedIP.Status.Assignment != nil && reservedIP.Status.Assignment.PodName == reservedIPAssociation.Spec.Assignment.PodName {
-				log.Info("Unassigning corresponding ReservedIP")
-				reservedIP.Spec.Assignment = nil
-				if err := r.Update(ctx, &reservedIP); err != nil {
-					return ctrl.Result{}, err
+			if forceAbandon {
+				if name := boundReservedIPName(&reservedIPAssociation); name != "" {
+					log.Info("Force abandon: leaving ReservedIP assigned", "reservedIP", name)
+					r.Recorder.Event(&reservedIPAssociation, "Warning", "Abandoned", fmt.Sprintf("Force abandoned without unassigning ReservedIP %s", name))
 				}
+			} else if err := r.releaseReservedIPs(ctx, &reservedIPAssociation, log); err != nil {
+				return ctrl.Result{}, err
 			}
-			reservedIPAssociation.ObjectMeta.Finalizers = removeString(reservedIPAssociation.ObjectMeta.Finalizers, finalizerName)
-			return ctrl.Result{}, r.Update(ctx, &reservedIPAssociation)
+			return ctrl.Result{}, removeFinalizer(ctx, r.Client, &reservedIPAssociation)
 		}
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// releaseReservedIPs unassigns the ReservedIPs claimed by the association.
+// They are found by the association annotation rather than the status, which
+// may not have been written yet when the association is deleted.
+func (r *ReservedIPAssociationReconciler) releaseReservedIPs(ctx context.Context, reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, log logr.Logger) error {
+	var reservedIPs ociv1alpha1.ReservedIPList
+	if err := r.List(ctx, &reservedIPs, client.InNamespace(reservedIPAssociation.Namespace)); err != nil {
+		return err
+	}
+	for i := range reservedIPs.Items {
+		reservedIP := &reservedIPs.Items[i]
+		if reservedIP.Annotations[ociv1alpha1.AssociationAnnotation] != reservedIPAssociation.Name {
+			continue
+		}
+		log.Info("Unassigning corresponding ReservedIP", "reservedIP", reservedIP.Name)
+		patch := mergeFrom(reservedIP)
+		reservedIP.Spec.Assignment = nil
+		delete(reservedIP.Annotations, ociv1alpha1.AssociationAnnotation)
+		if err := r.Patch(ctx, reservedIP, patch, fieldOwner); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followPod applies the association's pod lifecycle policy. It returns whether
+// the association was deleted and when to check the pod again.
+func (r *ReservedIPAssociationReconciler) followPod(ctx context.Context, reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, log logr.Logger) (bool, time.Duration, error) {
+	lifecycle := reservedIPAssociation.Spec.PodLifecycle
+	if lifecycle == nil {
+		return false, 0, nil
+	}
+
+	switch lifecycle.Policy {
+	case ociv1alpha1.PodLifecycleOwnerReference:
+		assignment := reservedIPAssociation.Spec.Assignment
+		if assignment == nil || assignment.PodName == "" {
+			return false, 0, nil
+		}
+		pod := &corev1.Pod{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: reservedIPAssociation.Namespace, Name: assignment.PodName}, pod); err != nil {
+			return false, 0, client.IgnoreNotFound(err)
+		}
+		for _, ref := range reservedIPAssociation.OwnerReferences {
+			if ref.UID == pod.UID {
+				return false, 0, nil
+			}
+		}
+		log.Info("Setting owner reference to pod", "pod", pod.Name)
+		patch := lockedMergeFrom(reservedIPAssociation)
+		reservedIPAssociation.OwnerReferences = append(reservedIPAssociation.OwnerReferences, metav1.OwnerReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       pod.Name,
+			UID:        pod.UID,
+		})
+		return false, 0, r.Patch(ctx, reservedIPAssociation, patch, fieldOwner)
+
+	case ociv1alpha1.PodLifecycleFollow:
+		alive, err := r.followedPodAlive(ctx, reservedIPAssociation)
+		if err != nil {
+			return false, 0, err
+		}
+		if alive {
+			if reservedIPAssociation.Status.PodGoneSince != nil {
+				patch := mergeFrom(reservedIPAssociation)
+				reservedIPAssociation.Status.PodGoneSince = nil
+				return false, 0, r.Status().Patch(ctx, reservedIPAssociation, patch, fieldOwner)
+			}
+			return false, 0, nil
+		}
+
+		if reservedIPAssociation.Status.PodGoneSince == nil {
+			// written on its own, so failing to bind the ReservedIP later on
+			// doesn't restart the grace period
+			log.Info("Followed pod is gone")
+			patch := mergeFrom(reservedIPAssociation)
+			now := metav1.Now()
+			reservedIPAssociation.Status.PodGoneSince = &now
+			if err := r.Status().Patch(ctx, reservedIPAssociation, patch, fieldOwner); err != nil {
+				return false, 0, err
+			}
+		}
+		var gracePeriod time.Duration
+		if lifecycle.GracePeriodSeconds != nil {
+			gracePeriod = time.Duration(*lifecycle.GracePeriodSeconds) * time.Second
+		}
+		if remaining := time.Until(reservedIPAssociation.Status.PodGoneSince.Add(gracePeriod)); remaining > 0 {
+			return false, remaining, nil
+		}
+
+		log.Info("Followed pod is gone; deleting association")
+		return true, 0, client.IgnoreNotFound(r.Delete(ctx, reservedIPAssociation))
+	}
+
+	return false, 0, nil
+}
+
+// followedPodAlive tells whether the pod followed by the association, or any
+// of the pods matching its pod selector, exists and hasn't terminated.
+// Associations without a pod to follow are always alive.
+func (r *ReservedIPAssociationReconciler) followedPodAlive(ctx context.Context, reservedIPAssociation *ociv1alpha1.ReservedIPAssociation) (bool, error) {
+	if podSelector := reservedIPAssociation.Spec.PodLifecycle.PodSelector; podSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(podSelector)
+		if err != nil {
+			// keep the association rather than deleting it for a typo
+			r.Recorder.Event(reservedIPAssociation, "Warning", "InvalidSpec", fmt.Sprintf("invalid spec.podLifecycle.podSelector: %s", err))
+			return true, nil
+		}
+		var pods corev1.PodList
+		if err := r.List(ctx, &pods, client.InNamespace(reservedIPAssociation.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return false, err
+		}
+		for i := range pods.Items {
+			if podAlive(&pods.Items[i]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	assignment := reservedIPAssociation.Spec.Assignment
+	if assignment == nil || assignment.PodName == "" {
+		return true, nil
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: reservedIPAssociation.Namespace, Name: assignment.PodName}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return podAlive(pod), nil
+}
+
+func podAlive(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp.IsZero() && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
+
+// boundReservedIPName returns the name of the ReservedIP the association refers
+// to, either by name or by an earlier selection.
+func boundReservedIPName(reservedIPAssociation *ociv1alpha1.ReservedIPAssociation) string {
+	if reservedIPAssociation.Spec.ReservedIPName != "" {
+		return reservedIPAssociation.Spec.ReservedIPName
+	}
+	return reservedIPAssociation.Status.ReservedIPName
+}
+
+// bindReservedIP writes the association's assignment to the ReservedIP once it
+// is allocated and not assigned to anything else, and reflects the outcome in
+// the association's status. ReservedIPs that aren't ready yet aren't an error;
+// the association is reconciled again when the ReservedIP changes.
+func (r *ReservedIPAssociationReconciler) bindReservedIP(ctx context.Context, reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, log logr.Logger) error {
+	spec := &reservedIPAssociation.Spec
+	if (spec.ReservedIPName == "") == (spec.ReservedIPSelector == nil) {
+		setAssociationPending(reservedIPAssociation, "InvalidSpec", "exactly one of spec.{reservedIPName,reservedIPSelector} needs to be defined")
+		return nil
+	}
+	if spec.Assignment == nil {
+		setAssociationPending(reservedIPAssociation, "InvalidSpec", "spec.assignment needs to be defined")
+		return nil
+	}
+
+	name := boundReservedIPName(reservedIPAssociation)
+	if name == "" {
+		return r.selectReservedIP(ctx, reservedIPAssociation, log)
+	}
+
+	var reservedIP ociv1alpha1.ReservedIP
+	if err := r.Client.Get(ctx, client.ObjectKey{
+		Namespace: reservedIPAssociation.Namespace,
+		Name:      name,
+	}, &reservedIP); err != nil {
+		if apierrors.IsNotFound(err) {
+			reservedIPAssociation.Status.ReservedIPName = ""
+			if spec.ReservedIPSelector != nil {
+				// the selected ReservedIP is gone, select another one
+				return r.selectReservedIP(ctx, reservedIPAssociation, log)
+			}
+			setAssociationPending(reservedIPAssociation, "ReservedIPNotFound", fmt.Sprintf("ReservedIP %s not found", name))
+			return nil
+		}
+		return err
+	}
+
+	if failover := reservedIP.Annotations[ociv1alpha1.FailoverAnnotation]; failover != "" {
+		setAssociationPending(reservedIPAssociation, "AlreadyAssigned", fmt.Sprintf("ReservedIP %s is managed by ReservedIPFailover %s", reservedIP.Name, failover))
+		return nil
+	}
+
+	owner := reservedIP.Annotations[ociv1alpha1.AssociationAnnotation]
+	if owner != "" && owner != reservedIPAssociation.Name && spec.ReservedIPSelector != nil {
+		// another association claimed the selected ReservedIP at the same
+		// time and won; select another one
+		log.Info("Selected ReservedIP was claimed by another association", "reservedIP", reservedIP.Name, "association", owner)
+		reservedIPAssociation.Status.ReservedIPName = ""
+		return r.selectReservedIP(ctx, reservedIPAssociation, log)
+	}
+	if owner != "" && owner != reservedIPAssociation.Name {
+		setAssociationPending(reservedIPAssociation, "AlreadyAssigned", fmt.Sprintf("ReservedIP %s is already assigned by ReservedIPAssociation %s", reservedIP.Name, owner))
+		return nil
+	}
+
+	if owner == "" && reservedIP.Spec.Assignment != nil && !reflect.DeepEqual(*reservedIP.Spec.Assignment, *spec.Assignment) {
+		setAssociationPending(reservedIPAssociation, "AlreadyAssigned", fmt.Sprintf("ReservedIP %s is already assigned to %s", reservedIP.Name, describeAssignment(reservedIP.Spec.Assignment)))
+		return nil
+	}
+
+	if owner == "" && reservedIP.Spec.Assignment == nil && reservedIP.Status.State != "allocated" {
+		setAssociationPending(reservedIPAssociation, "ReservedIPNotAllocated", fmt.Sprintf("ReservedIP %s is in state %q, waiting for it to be allocated", reservedIP.Name, reservedIP.Status.State))
+		return nil
+	}
+
+	// the ReservedIP is either unassigned, already assigned by this
+	// association or manually assigned to the same target; take ownership and
+	// make sure the assignment follows the association's spec
+	if owner == "" || reservedIP.Spec.Assignment == nil || !reflect.DeepEqual(*reservedIP.Spec.Assignment, *spec.Assignment) {
+		log.Info("Assigning ReservedIP", "reservedIP", reservedIP.Name)
+		if err := r.claimReservedIP(ctx, reservedIPAssociation, &reservedIP); err != nil {
+			return err
+		}
+	}
+
+	setAssociationBound(reservedIPAssociation, &reservedIP)
+	return nil
+}
+
+// selectReservedIP binds the first allocated and unassigned ReservedIP matching
+// the association's selector. If another association claims the same
+// ReservedIP at the same time, the last claim wins and the other association
+// selects again when it is reconciled next.
+func (r *ReservedIPAssociationReconciler) selectReservedIP(ctx context.Context, reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, log logr.Logger) error {
+	selector, err := metav1.LabelSelectorAsSelector(reservedIPAssociation.Spec.ReservedIPSelector)
+	if err != nil {
+		setAssociationPending(reservedIPAssociation, "InvalidSpec", fmt.Sprintf("invalid spec.reservedIPSelector: %s", err))
+		return nil
+	}
+
+	var reservedIPs ociv1alpha1.ReservedIPList
+	if err := r.List(ctx, &reservedIPs, client.InNamespace(reservedIPAssociation.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+	sort.Slice(reservedIPs.Items, func(i, j int) bool {
+		return reservedIPs.Items[i].Name < reservedIPs.Items[j].Name
+	})
+
+	for i := range reservedIPs.Items {
+		if reservedIPs.Items[i].Annotations[ociv1alpha1.AssociationAnnotation] == reservedIPAssociation.Name {
+			// claimed earlier, but the selection didn't make it to the status
+			setAssociationBound(reservedIPAssociation, &reservedIPs.Items[i])
+			return nil
+		}
+	}
+
+	for i := range reservedIPs.Items {
+		reservedIP := &reservedIPs.Items[i]
+		if !reservedIP.DeletionTimestamp.IsZero() ||
+			reservedIP.Annotations[ociv1alpha1.AssociationAnnotation] != "" ||
+			reservedIP.Annotations[ociv1alpha1.FailoverAnnotation] != "" ||
+			reservedIP.Spec.Assignment != nil ||
+			reservedIP.Status.State != "allocated" {
+			continue
+		}
+
+		if err := r.claimReservedIP(ctx, reservedIPAssociation, reservedIP); err != nil {
+			return err
+		}
+
+		log.Info("Selected ReservedIP", "reservedIP", reservedIP.Name)
+		setAssociationBound(reservedIPAssociation, reservedIP)
+		return nil
+	}
+
+	setAssociationPending(reservedIPAssociation, "NoFreeReservedIP", "no allocated and unassigned ReservedIP matches spec.reservedIPSelector")
+	return nil
+}
+
+// claimReservedIP records the association as owner of the ReservedIP and
+// writes its assignment. Only these fields are patched, so changes of the
+// status of the ReservedIP don't make the claim fail.
+func (r *ReservedIPAssociationReconciler) claimReservedIP(ctx context.Context, reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, reservedIP *ociv1alpha1.ReservedIP) error {
+	patch := mergeFrom(reservedIP)
+	if reservedIP.Annotations == nil {
+		reservedIP.Annotations = map[string]string{}
+	}
+	reservedIP.Annotations[ociv1alpha1.AssociationAnnotation] = reservedIPAssociation.Name
+	reservedIP.Spec.Assignment = reservedIPAssociation.Spec.Assignment.DeepCopy()
+	return r.Patch(ctx, reservedIP, patch, fieldOwner)
+}
+
+func setAssociationBound(reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, reservedIP *ociv1alpha1.ReservedIP) {
+	reservedIPAssociation.Status.ReservedIPName = reservedIP.Name
+	reservedIPAssociation.Status.Phase = "Bound"
+	if reservedIP.Status.State == "assigned" && reservedIP.Status.Assignment != nil && reservedIP.Status.Assignment.MatchesSpec(*reservedIPAssociation.Spec.Assignment) {
+		reservedIPAssociation.Status.Phase = "Assigned"
+	}
+	meta.SetStatusCondition(&reservedIPAssociation.Status.Conditions, metav1.Condition{
+		Type:               ociv1alpha1.ReservedIPAssociationBound,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Bound",
+		Message:            fmt.Sprintf("ReservedIP %s is assigned to %s", reservedIP.Name, describeAssignment(reservedIPAssociation.Spec.Assignment)),
+		ObservedGeneration: reservedIPAssociation.Generation,
+	})
+}
+
+func setAssociationPending(reservedIPAssociation *ociv1alpha1.ReservedIPAssociation, reason, message string) {
+	reservedIPAssociation.Status.Phase = "Pending"
+	meta.SetStatusCondition(&reservedIPAssociation.Status.Conditions, metav1.Condition{
+		Type:               ociv1alpha1.ReservedIPAssociationBound,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: reservedIPAssociation.Generation,
+	})
+}
+
+func describeAssignment(assignment *ociv1alpha1.ReservedIPAssignment) string {
+	if assignment.LeaseName != "" {
+		return fmt.Sprintf("holder of Lease %s", assignment.LeaseName)
+	}
+	if assignment.PodName != "" {
+		return fmt.Sprintf("pod %s", assignment.PodName)
+	}
+	return fmt.Sprintf("private IP %s", assignment.PrivateIPAddress)
+}
+
 func (r *ReservedIPAssociationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ReservedIPAssociation{}, reservedIPNameField, func(obj client.Object) []string {
+		return []string{boundReservedIPName(obj.(*ociv1alpha1.ReservedIPAssociation))}
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ReservedIPAssociation{}, podNameField, func(obj client.Object) []string {
+		assignment := obj.(*ociv1alpha1.ReservedIPAssociation).Spec.Assignment
+		if assignment == nil || assignment.PodName == "" {
+			return nil
+		}
+		return []string{assignment.PodName}
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ociv1alpha1.ReservedIPAssociation{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			var associations ociv1alpha1.ReservedIPAssociationList
+			if err := mgr.GetClient().List(context.Background(), &associations,
+				client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{podNameField: obj.GetName()}); err != nil {
+				return nil
+			}
+			var requests []reconcile.Request
+			for _, association := range associations.Items {
+				if association.Spec.PodLifecycle != nil {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: association.Namespace, Name: association.Name}})
+				}
+			}
+
+			// associations following pods by label
+			if err := mgr.GetClient().List(context.Background(), &associations, client.InNamespace(obj.GetNamespace())); err != nil {
+				return requests
+			}
+			for _, association := range associations.Items {
+				lifecycle := association.Spec.PodLifecycle
+				if lifecycle == nil || lifecycle.PodSelector == nil {
+					continue
+				}
+				selector, err := metav1.LabelSelectorAsSelector(lifecycle.PodSelector)
+				if err != nil || !selector.Matches(labels.Set(obj.GetLabels())) {
+					continue
+				}
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: association.Namespace, Name: association.Name}})
+			}
+			return requests
+		})).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIP{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			var associations ociv1alpha1.ReservedIPAssociationList
+			if err := mgr.GetClient().List(context.Background(), &associations,
+				client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{reservedIPNameField: obj.GetName()}); err != nil {
+				return nil
+			}
+			requests := make([]reconcile.Request, 0, len(associations.Items))
+			for _, association := range associations.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: association.Namespace, Name: association.Name}})
+			}
+
+			// unbound selector-based associations might be waiting for this ReservedIP
+			if err := mgr.GetClient().List(context.Background(), &associations,
+				client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{reservedIPNameField: ""}); err != nil {
+				return requests
+			}
+			for _, association := range associations.Items {
+				selector, err := metav1.LabelSelectorAsSelector(association.Spec.ReservedIPSelector)
+				if err != nil || association.Spec.ReservedIPSelector == nil || !selector.Matches(labels.Set(obj.GetLabels())) {
+					continue
+				}
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: association.Namespace, Name: association.Name}})
+			}
+			return requests
+		})).
 		Complete(r)
 }
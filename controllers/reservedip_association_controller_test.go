@@ -0,0 +1,509 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func newAssociationReconciler(t *testing.T, objs ...client.Object) *ReservedIPAssociationReconciler {
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &ReservedIPAssociationReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+// claimedReservedIP returns an allocated ReservedIP claimed by association
+func claimedReservedIP(name, association string, assignment *ociv1alpha1.ReservedIPAssignment) *ociv1alpha1.ReservedIP {
+	reservedIP := testReservedIP(name, assignment)
+	reservedIP.Labels = map[string]string{"pool": "gateways"}
+	if association != "" {
+		reservedIP.Annotations = map[string]string{ociv1alpha1.AssociationAnnotation: association}
+	}
+	reservedIP.Status.State = "allocated"
+	return reservedIP
+}
+
+func TestReservedIPAssociationDelete(t *testing.T) {
+	pod := &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-0"}
+	// deleted before the selected ReservedIP was written to the status
+	association := &ociv1alpha1.ReservedIPAssociation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              "gateway-0",
+			Finalizers:        []string{finalizerName},
+			DeletionTimestamp: &testCreated,
+		},
+		Spec: ociv1alpha1.ReservedIPAssociationSpec{
+			ReservedIPSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gateways"}},
+			Assignment:         pod,
+		},
+	}
+	r := newAssociationReconciler(t,
+		association,
+		claimedReservedIP("a", "gateway-0", pod),
+		claimedReservedIP("b", "gateway-1", &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-1"}),
+	)
+
+	ctx := context.Background()
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(association)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var a, b ociv1alpha1.ReservedIP
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "a"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Spec.Assignment != nil {
+		t.Errorf("a.spec.assignment = %+v, want nil", a.Spec.Assignment)
+	}
+	if owner, ok := a.Annotations[ociv1alpha1.AssociationAnnotation]; ok {
+		t.Errorf("a is still claimed by %q", owner)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "b"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Spec.Assignment == nil || b.Annotations[ociv1alpha1.AssociationAnnotation] != "gateway-1" {
+		t.Errorf("b of another association was changed: %+v %v", b.Spec.Assignment, b.Annotations)
+	}
+}
+
+func TestClaimReservedIPIgnoresStatusChanges(t *testing.T) {
+	r := newAssociationReconciler(t, claimedReservedIP("a", "", nil))
+	ctx := context.Background()
+
+	var stale ociv1alpha1.ReservedIP
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "a"}, &stale); err != nil {
+		t.Fatal(err)
+	}
+	// the ReservedIP controller writes the status in between
+	updated := stale.DeepCopy()
+	updated.Status.PublicIPAddress = "192.0.2.1"
+	if err := r.Status().Update(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+
+	association := &ociv1alpha1.ReservedIPAssociation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway-0"},
+		Spec:       ociv1alpha1.ReservedIPAssociationSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-0"}},
+	}
+	if err := r.claimReservedIP(ctx, association, &stale); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+
+	var got ociv1alpha1.ReservedIP
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "a"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Annotations[ociv1alpha1.AssociationAnnotation] != "gateway-0" || !reflect.DeepEqual(got.Spec.Assignment, association.Spec.Assignment) {
+		t.Errorf("ReservedIP not claimed: %v %+v", got.Annotations, got.Spec.Assignment)
+	}
+	if got.Status.PublicIPAddress != "192.0.2.1" {
+		t.Errorf("claim overwrote the status: %+v", got.Status)
+	}
+}
+
+func TestBindReservedIPSelector(t *testing.T) {
+	tests := []struct {
+		name        string
+		bound       string
+		reservedIPs []client.Object
+		want        string
+		wantPhase   string
+	}{
+		{
+			name:        "selects the first free ReservedIP",
+			reservedIPs: []client.Object{claimedReservedIP("b", "", nil), claimedReservedIP("a", "", nil)},
+			want:        "a",
+			wantPhase:   "Bound",
+		},
+		{
+			name:        "finds an earlier claim missing from the status",
+			reservedIPs: []client.Object{claimedReservedIP("a", "", nil), claimedReservedIP("b", "gateway-0", &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-0"})},
+			want:        "b",
+			wantPhase:   "Bound",
+		},
+		{
+			name:        "selects again after losing a concurrent claim",
+			bound:       "a",
+			reservedIPs: []client.Object{claimedReservedIP("a", "gateway-1", &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-1"}), claimedReservedIP("b", "", nil)},
+			want:        "b",
+			wantPhase:   "Bound",
+		},
+		{
+			name:        "pending without free ReservedIPs",
+			reservedIPs: []client.Object{claimedReservedIP("a", "gateway-1", &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-1"})},
+			wantPhase:   "Pending",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			association := &ociv1alpha1.ReservedIPAssociation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway-0"},
+				Spec: ociv1alpha1.ReservedIPAssociationSpec{
+					ReservedIPSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gateways"}},
+					Assignment:         &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-0"},
+				},
+				Status: ociv1alpha1.ReservedIPAssociationStatus{ReservedIPName: tt.bound},
+			}
+			r := newAssociationReconciler(t, tt.reservedIPs...)
+			ctx := context.Background()
+			if err := r.bindReservedIP(ctx, association, logr.Discard()); err != nil {
+				t.Fatal(err)
+			}
+			if association.Status.ReservedIPName != tt.want || association.Status.Phase != tt.wantPhase {
+				t.Errorf("bound %q in phase %s, want %q in phase %s", association.Status.ReservedIPName, association.Status.Phase, tt.want, tt.wantPhase)
+			}
+			if tt.want == "" {
+				return
+			}
+			var reservedIP ociv1alpha1.ReservedIP
+			if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: tt.want}, &reservedIP); err != nil {
+				t.Fatal(err)
+			}
+			if reservedIP.Annotations[ociv1alpha1.AssociationAnnotation] != "gateway-0" {
+				t.Errorf("%s claimed by %q", tt.want, reservedIP.Annotations[ociv1alpha1.AssociationAnnotation])
+			}
+		})
+	}
+}
+
+func TestFollowPod(t *testing.T) {
+	// testPod returns a pod of the gateway pool in phase
+	testPod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": "gateway"}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	other := testPod("other", corev1.PodRunning)
+	other.Labels = map[string]string{"app": "web"}
+	deleting := testPod("gateway-0", corev1.PodRunning)
+	deleting.DeletionTimestamp = &testCreated
+	deleting.Finalizers = []string{"test"}
+	recently := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	longAgo := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	tests := []struct {
+		name         string
+		podSelector  *metav1.LabelSelector
+		podGoneSince *metav1.Time
+		pods         []client.Object
+		wantDeleted  bool
+		wantGone     bool
+		wantRequeue  bool
+	}{
+		{
+			name: "keeps the association while the pod runs",
+			pods: []client.Object{testPod("gateway-0", corev1.PodRunning)},
+		},
+		{
+			name:         "clears podGoneSince when the pod is back",
+			podGoneSince: &recently,
+			pods:         []client.Object{testPod("gateway-0", corev1.PodPending)},
+		},
+		{
+			name:        "waits for the grace period after the pod is deleted",
+			wantGone:    true,
+			wantRequeue: true,
+		},
+		{
+			name:         "keeps waiting from the time the pod was first gone",
+			podGoneSince: &recently,
+			pods:         []client.Object{deleting},
+			wantGone:     true,
+			wantRequeue:  true,
+		},
+		{
+			name:         "deletes the association after the grace period",
+			podGoneSince: &longAgo,
+			pods:         []client.Object{testPod("gateway-0", corev1.PodFailed)},
+			wantDeleted:  true,
+		},
+		{
+			name:        "keeps the association while a selected pod runs",
+			podSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "gateway"}},
+			pods:        []client.Object{testPod("gateway-1", corev1.PodSucceeded), testPod("gateway-2", corev1.PodRunning)},
+		},
+		{
+			name:         "deletes the association when all selected pods are gone",
+			podSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "gateway"}},
+			podGoneSince: &longAgo,
+			pods:         []client.Object{testPod("gateway-1", corev1.PodFailed), other},
+			wantDeleted:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			association := &ociv1alpha1.ReservedIPAssociation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway-0"},
+				Spec: ociv1alpha1.ReservedIPAssociationSpec{
+					ReservedIPName: "a",
+					Assignment:     &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-0"},
+					PodLifecycle: &ociv1alpha1.PodLifecycle{
+						Policy:             ociv1alpha1.PodLifecycleFollow,
+						GracePeriodSeconds: int32Ptr(300),
+						PodSelector:        tt.podSelector,
+					},
+				},
+				Status: ociv1alpha1.ReservedIPAssociationStatus{PodGoneSince: tt.podGoneSince},
+			}
+			r := newAssociationReconciler(t, append(tt.pods, association.DeepCopy())...)
+			ctx := context.Background()
+
+			deleted, requeueAfter, err := r.followPod(ctx, association, logr.Discard())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != tt.wantDeleted || (requeueAfter > 0) != tt.wantRequeue {
+				t.Errorf("followPod() = %v, %v, want deleted %v and requeue %v", deleted, requeueAfter, tt.wantDeleted, tt.wantRequeue)
+			}
+
+			var got ociv1alpha1.ReservedIPAssociation
+			err = r.Get(ctx, client.ObjectKeyFromObject(association), &got)
+			if tt.wantDeleted {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("association not deleted: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// podGoneSince must be persisted by followPod itself
+			if gone := got.Status.PodGoneSince != nil; gone != tt.wantGone {
+				t.Errorf("persisted podGoneSince = %v, want set %v", got.Status.PodGoneSince, tt.wantGone)
+			}
+			if tt.podGoneSince != nil && tt.wantGone && !got.Status.PodGoneSince.Equal(tt.podGoneSince) {
+				t.Errorf("podGoneSince moved from %v to %v", tt.podGoneSince, got.Status.PodGoneSince)
+			}
+		})
+	}
+}
+
+func TestBindReservedIP(t *testing.T) {
+	pod := &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-0"}
+	withState := func(reservedIP *ociv1alpha1.ReservedIP, state string) *ociv1alpha1.ReservedIP {
+		reservedIP.Status.State = state
+		return reservedIP
+	}
+	assigned := withState(claimedReservedIP("a", "gateway-0", pod), "assigned")
+	assigned.Status.Assignment = pod.DeepCopy()
+	failover := claimedReservedIP("a", "", nil)
+	failover.Annotations = map[string]string{ociv1alpha1.FailoverAnnotation: "gateways"}
+
+	tests := []struct {
+		name       string
+		spec       ociv1alpha1.ReservedIPAssociationSpec
+		reservedIP *ociv1alpha1.ReservedIP
+		wantPhase  string
+		wantReason string
+		wantClaim  bool
+	}{
+		{
+			name:       "neither name nor selector",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{Assignment: pod},
+			wantPhase:  "Pending",
+			wantReason: "InvalidSpec",
+		},
+		{
+			name:       "without assignment",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a"},
+			wantPhase:  "Pending",
+			wantReason: "InvalidSpec",
+		},
+		{
+			name:       "ReservedIP not found",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: pod},
+			wantPhase:  "Pending",
+			wantReason: "ReservedIPNotFound",
+		},
+		{
+			name:       "ReservedIP not allocated yet",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: pod},
+			reservedIP: withState(claimedReservedIP("a", "", nil), ""),
+			wantPhase:  "Pending",
+			wantReason: "ReservedIPNotAllocated",
+		},
+		{
+			name:       "ReservedIP of another association",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: pod},
+			reservedIP: claimedReservedIP("a", "gateway-1", &ociv1alpha1.ReservedIPAssignment{PodName: "gateway-1"}),
+			wantPhase:  "Pending",
+			wantReason: "AlreadyAssigned",
+		},
+		{
+			name:       "ReservedIP of a failover",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: pod},
+			reservedIP: failover,
+			wantPhase:  "Pending",
+			wantReason: "AlreadyAssigned",
+		},
+		{
+			name:       "allocated ReservedIP",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: pod},
+			reservedIP: claimedReservedIP("a", "", nil),
+			wantPhase:  "Bound",
+			wantReason: "Bound",
+			wantClaim:  true,
+		},
+		{
+			name:       "assigned ReservedIP",
+			spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: pod},
+			reservedIP: assigned,
+			wantPhase:  "Assigned",
+			wantReason: "Bound",
+			wantClaim:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []client.Object
+			if tt.reservedIP != nil {
+				objs = append(objs, tt.reservedIP.DeepCopy())
+			}
+			r := newAssociationReconciler(t, objs...)
+			association := &ociv1alpha1.ReservedIPAssociation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway-0"},
+				Spec:       tt.spec,
+			}
+			ctx := context.Background()
+			if err := r.bindReservedIP(ctx, association, logr.Discard()); err != nil {
+				t.Fatal(err)
+			}
+
+			condition := meta.FindStatusCondition(association.Status.Conditions, ociv1alpha1.ReservedIPAssociationBound)
+			if association.Status.Phase != tt.wantPhase || condition == nil || condition.Reason != tt.wantReason {
+				t.Errorf("phase %s with condition %+v, want phase %s with reason %s", association.Status.Phase, condition, tt.wantPhase, tt.wantReason)
+			}
+			if tt.reservedIP == nil {
+				return
+			}
+			var got ociv1alpha1.ReservedIP
+			if err := r.Get(ctx, client.ObjectKeyFromObject(tt.reservedIP), &got); err != nil {
+				t.Fatal(err)
+			}
+			if claimed := got.Annotations[ociv1alpha1.AssociationAnnotation] == "gateway-0"; claimed != tt.wantClaim {
+				t.Errorf("claimed = %v, want %v", claimed, tt.wantClaim)
+			}
+		})
+	}
+}
+
+func TestReservedIPAssociationPrivateIP(t *testing.T) {
+	target := &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.0.5"}
+	other := &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.0.6"}
+
+	tests := []struct {
+		name           string
+		reservedIP     *ociv1alpha1.ReservedIP
+		wantPhase      string
+		wantAssignment *ociv1alpha1.ReservedIPAssignment
+	}{
+		{
+			name:           "adopts a manual assignment to the same private IP",
+			reservedIP:     claimedReservedIP("a", "", target.DeepCopy()),
+			wantPhase:      "Bound",
+			wantAssignment: target,
+		},
+		{
+			name:           "doesn't take over a manual assignment to another private IP",
+			reservedIP:     claimedReservedIP("a", "", other.DeepCopy()),
+			wantPhase:      "Pending",
+			wantAssignment: other,
+		},
+		{
+			name:           "applies a changed private IP",
+			reservedIP:     claimedReservedIP("a", "private", other.DeepCopy()),
+			wantPhase:      "Bound",
+			wantAssignment: target,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			association := &ociv1alpha1.ReservedIPAssociation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "private", Finalizers: []string{finalizerName}},
+				Spec:       ociv1alpha1.ReservedIPAssociationSpec{ReservedIPName: "a", Assignment: target.DeepCopy()},
+			}
+			r := newAssociationReconciler(t, association, tt.reservedIP)
+			ctx := context.Background()
+			key := client.ObjectKeyFromObject(association)
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			var got ociv1alpha1.ReservedIPAssociation
+			if err := r.Get(ctx, key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", got.Status.Phase, tt.wantPhase)
+			}
+			var reservedIP ociv1alpha1.ReservedIP
+			if err := r.Get(ctx, client.ObjectKeyFromObject(tt.reservedIP), &reservedIP); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(reservedIP.Spec.Assignment, tt.wantAssignment) {
+				t.Errorf("spec.assignment = %+v, want %+v", reservedIP.Spec.Assignment, tt.wantAssignment)
+			}
+			if tt.wantPhase != "Bound" {
+				return
+			}
+
+			// deleting the association unassigns the private IP again
+			if err := r.Delete(ctx, &got); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(tt.reservedIP), &reservedIP); err != nil {
+				t.Fatal(err)
+			}
+			if reservedIP.Spec.Assignment != nil || reservedIP.Annotations[ociv1alpha1.AssociationAnnotation] != "" {
+				t.Errorf("ReservedIP not unassigned: %+v %v", reservedIP.Spec.Assignment, reservedIP.Annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// specTargetKeys returns the pod, private IP or Lease the spec of the
+// ReservedIP wants to be assigned to, as "pod/<namespace>/<name>",
+// "ip/<address>" or "lease/<namespace>/<name>".
+func specTargetKeys(reservedIP reservedIPObject) []string {
+	assignment := reservedIP.GetSpec().Assignment
+	if assignment == nil {
+		return nil
+	}
+	namespace := reservedIP.GetNamespace()
+	if namespace == "" {
+		namespace = assignment.Namespace
+	}
+	switch {
+	case assignment.LeaseName != "":
+		return []string{"lease/" + namespace + "/" + assignment.LeaseName}
+	case assignment.PodName != "":
+		return []string{"pod/" + namespace + "/" + assignment.PodName}
+	case assignment.PrivateIPAddress != "":
+		return []string{"ip/" + assignment.PrivateIPAddress}
+	}
+	return nil
+}
+
+// statusTargetKeys returns the pod and private IP the ReservedIP is assigned
+// to, in the format of specTargetKeys.
+func statusTargetKeys(reservedIP reservedIPObject) []string {
+	status := reservedIP.GetStatus()
+	if status.Assignment == nil {
+		return nil
+	}
+	var keys []string
+	if status.Assignment.PodName != "" {
+		namespace := reservedIP.GetNamespace()
+		if namespace == "" {
+			namespace = status.Assignment.Namespace
+		}
+		keys = append(keys, "pod/"+namespace+"/"+status.Assignment.PodName)
+	}
+	if status.Assignment.PrivateIPAddress != "" {
+		keys = append(keys, "ip/"+status.Assignment.PrivateIPAddress)
+	}
+	return keys
+}
+
+// assignmentTargetIndexKey returns the targets of the spec and status of the
+// ReservedIP, for indexing under assignmentTargetField.
+func assignmentTargetIndexKey(obj client.Object) []string {
+	reservedIP := obj.(reservedIPObject)
+	keys := specTargetKeys(reservedIP)
+	for _, key := range statusTargetKeys(reservedIP) {
+		if !containsString(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// targets reports whether the ReservedIP wants to be assigned to any of the
+// given targets. ReservedIPs that are assigned to what their spec asks for
+// also target the pod and private IP in their status, so a ReservedIP for a
+// pod and one for its private IP are found to conflict. ReservedIPs that
+// may not be assigned, see mayAssign, don't target anything.
+func targets(reservedIP reservedIPObject, keys []string) bool {
+	if !reservedIP.GetDeletionTimestamp().IsZero() || !mayAssign(reservedIP.GetStatus()) {
+		return false
+	}
+	own := specTargetKeys(reservedIP)
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	if status.State == "assigned" && spec.Assignment != nil && status.Assignment != nil && status.Assignment.MatchesSpec(*spec.Assignment) {
+		own = append(own, statusTargetKeys(reservedIP)...)
+	}
+	for _, key := range own {
+		if containsString(keys, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayAssign reports whether the ReservedIP may be assigned as far as its
+// status tells; expired ReservedIPs and those violating a policy or quota
+// don't keep others from being assigned.
+func mayAssign(status *ociv1alpha1.ReservedIPStatus) bool {
+	return !isExpired(status) &&
+		!meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPPolicyViolation) &&
+		!meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPQuotaExceeded)
+}
+
+// precedes reports whether a wins over b when both target the same pod,
+// private IP or Lease: the higher priority wins, then the older object, and
+// the name as a last resort so all reconciles agree on the winner.
+func precedes(a, b reservedIPObject) bool {
+	pa, pb := a.GetSpec().Assignment.Priority, b.GetSpec().Assignment.Priority
+	if pa != pb {
+		return pa > pb
+	}
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return describeReservedIP(a) < describeReservedIP(b)
+}
+
+// describeReservedIP returns the kind and name of the ReservedIP for messages.
+func describeReservedIP(reservedIP reservedIPObject) string {
+	if reservedIP.GetNamespace() == "" {
+		return "ClusterReservedIP " + reservedIP.GetName()
+	}
+	return "ReservedIP " + reservedIP.GetNamespace() + "/" + reservedIP.GetName()
+}
+
+// listTargeting returns all ReservedIPs and ClusterReservedIPs indexed under
+// any of the given targets.
+func listTargeting(ctx context.Context, c client.Reader, keys []string) ([]reservedIPObject, error) {
+	var result []reservedIPObject
+	seen := map[types.UID]bool{}
+	for _, key := range keys {
+		var reservedIPs ociv1alpha1.ReservedIPList
+		if err := c.List(ctx, &reservedIPs, client.MatchingFields{assignmentTargetField: key}); err != nil {
+			return nil, err
+		}
+		var clusterReservedIPs ociv1alpha1.ClusterReservedIPList
+		if err := c.List(ctx, &clusterReservedIPs, client.MatchingFields{assignmentTargetField: key}); err != nil {
+			return nil, err
+		}
+		for i := range reservedIPs.Items {
+			if item := &reservedIPs.Items[i]; !seen[item.UID] {
+				seen[item.UID] = true
+				result = append(result, item)
+			}
+		}
+		for i := range clusterReservedIPs.Items {
+			if item := &clusterReservedIPs.Items[i]; !seen[item.UID] {
+				seen[item.UID] = true
+				result = append(result, item)
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkConflict looks for other ReservedIPs and ClusterReservedIPs targeting
+// the given pod, private IP or Lease. If one of them takes precedence, it sets
+// the Conflict condition and returns true; the ReservedIP must then be left
+// alone in OCI, or both would keep stealing the private IP from each other.
+func (r *ReservedIPReconciler) checkConflict(ctx context.Context, reservedIP reservedIPObject, keys []string, log logr.Logger) (bool, error) {
+	others, err := listTargeting(ctx, r.Client, keys)
+	if err != nil {
+		return false, err
+	}
+
+	var winner reservedIPObject
+	for _, other := range others {
+		if other.GetUID() == reservedIP.GetUID() || !targets(other, keys) {
+			continue
+		}
+		if precedes(other, reservedIP) && (winner == nil || precedes(other, winner)) {
+			winner = other
+		}
+	}
+
+	status := reservedIP.GetStatus()
+	if winner == nil {
+		if !meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPConflict) {
+			return false, nil
+		}
+		log.Info("conflict resolved")
+		patch := mergeFrom(reservedIP)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ociv1alpha1.ReservedIPConflict,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Resolved",
+			Message: "No other ReservedIP targets the same pod, private IP or Lease",
+		})
+		return false, r.Status().Patch(ctx, reservedIP, patch, fieldOwner)
+	}
+
+	message := fmt.Sprintf("%s targets the same pod, private IP or Lease and takes precedence", describeReservedIP(winner))
+	condition := meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPConflict)
+	if condition != nil && condition.Status == metav1.ConditionTrue && condition.Message == message {
+		return true, nil
+	}
+	log.Info("conflicting ReservedIP takes precedence; not assigning", "winner", describeReservedIP(winner))
+	patch := mergeFrom(reservedIP)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    ociv1alpha1.ReservedIPConflict,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Conflict",
+		Message: message,
+	})
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return true, err
+	}
+	r.Recorder.Event(reservedIP, "Warning", "Conflict", "Not assigned: "+message)
+	return true, nil
+}
+
+// resetAssignmentConditions sets the Conflict and ForeignPublicIP conditions
+// to False, as they only explain why a wanted assignment isn't made. It
+// returns whether any of them changed.
+func resetAssignmentConditions(status *ociv1alpha1.ReservedIPStatus) bool {
+	changed := false
+	for _, conditionType := range []string{ociv1alpha1.ReservedIPConflict, ociv1alpha1.ReservedIPForeignPublicIP} {
+		if meta.IsStatusConditionTrue(status.Conditions, conditionType) {
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:    conditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Unassigned",
+				Message: "The ReservedIP is not to be assigned",
+			})
+			changed = true
+		}
+	}
+	return changed
+}
+
+// clearAssignmentConditions resets the Conflict and ForeignPublicIP conditions
+// of a ReservedIP without spec.assignment.
+func (r *ReservedIPReconciler) clearAssignmentConditions(ctx context.Context, reservedIP reservedIPObject) error {
+	patch := mergeFrom(reservedIP)
+	if !resetAssignmentConditions(reservedIP.GetStatus()) {
+		return nil
+	}
+	return r.Status().Patch(ctx, reservedIP, patch, fieldOwner)
+}
+
+// enqueueConflicting enqueues all objects of the given list type that target
+// the same pods, private IPs or Leases as the changed ReservedIP or
+// ClusterReservedIP, so losers of a conflict take over when the winner is
+// unassigned or deleted.
+func enqueueConflicting(c client.Client, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+		for _, key := range assignmentTargetIndexKey(obj) {
+			list := list.DeepCopyObject().(client.ObjectList)
+			if err := c.List(context.Background(), list, client.MatchingFields{assignmentTargetField: key}); err != nil {
+				return nil
+			}
+			_ = meta.EachListItem(list, func(item runtime.Object) error {
+				o := item.(client.Object)
+				if o.GetUID() != obj.GetUID() {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}})
+				}
+				return nil
+			})
+		}
+		return requests
+	})
+}
+
+// clusterTag is the freeform tag holding the cluster ID on the public IPs the
+// operator allocates
+const clusterTag = "k8s-oci-operator-cluster"
+
+// ownsPublicIP reports whether the public IP was allocated by the operator of
+// this cluster, i.e. it is tagged with the cluster ID. A shared display name
+// prefix isn't enough, as operators of several clusters may use the same one.
+func (r *ReservedIPReconciler) ownsPublicIP(publicIP ocicore.PublicIp) bool {
+	return r.ClusterID != "" && publicIP.FreeformTags[clusterTag] == r.ClusterID
+}
+
+// refuseTakeover sets the ForeignPublicIP condition and fails the assignment,
+// as the target private IP has a reserved public IP of someone else that must
+// not be unassigned without spec.assignment.allowTakeover.
+func (r *ReservedIPReconciler) refuseTakeover(ctx context.Context, reservedIP reservedIPObject, publicIP ocicore.PublicIp, privateIP string, log logr.Logger) error {
+	message := fmt.Sprintf("Private IP %s has reserved public IP %s that wasn't allocated by the operator; set spec.assignment.allowTakeover to unassign it", privateIP, *publicIP.Id)
+	log.Info("not unassigning foreign public IP from private IP", "privateIP", privateIP, "foreignPublicIPID", *publicIP.Id)
+
+	status := reservedIP.GetStatus()
+	condition := meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPForeignPublicIP)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Message != message {
+		patch := mergeFrom(reservedIP)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ociv1alpha1.ReservedIPForeignPublicIP,
+			Status:  metav1.ConditionTrue,
+			Reason:  "TakeoverRefused",
+			Message: message,
+		})
+		if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+			return err
+		}
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,443 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+var testCreated = metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+func testReservedIP(name string, assignment *ociv1alpha1.ReservedIPAssignment) *ociv1alpha1.ReservedIP {
+	return &ociv1alpha1.ReservedIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: testCreated},
+		Spec:       ociv1alpha1.ReservedIPSpec{Assignment: assignment},
+	}
+}
+
+func TestPrecedes(t *testing.T) {
+	pod := ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}
+	withPriority := func(priority int32) *ociv1alpha1.ReservedIPAssignment {
+		assignment := pod
+		assignment.Priority = priority
+		return &assignment
+	}
+	older := testReservedIP("b", &pod)
+	older.CreationTimestamp = metav1.NewTime(testCreated.Add(-time.Hour))
+	cluster := &ociv1alpha1.ClusterReservedIP{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", CreationTimestamp: testCreated},
+		Spec:       ociv1alpha1.ReservedIPSpec{Assignment: &pod},
+	}
+
+	tests := []struct {
+		name string
+		a, b reservedIPObject
+		want bool
+	}{
+		{
+			name: "higher priority wins",
+			a:    testReservedIP("b", withPriority(10)),
+			b:    older,
+			want: true,
+		},
+		{
+			name: "lower priority loses",
+			a:    older,
+			b:    testReservedIP("b", withPriority(10)),
+			want: false,
+		},
+		{
+			name: "older wins with the same priority",
+			a:    older,
+			b:    testReservedIP("a", &pod),
+			want: true,
+		},
+		{
+			name: "newer loses with the same priority",
+			a:    testReservedIP("a", &pod),
+			b:    older,
+			want: false,
+		},
+		{
+			name: "name decides between equals",
+			a:    testReservedIP("a", &pod),
+			b:    testReservedIP("b", &pod),
+			want: true,
+		},
+		{
+			name: "ClusterReservedIP sorts before ReservedIP",
+			a:    cluster,
+			b:    testReservedIP("a", &pod),
+			want: true,
+		},
+		{
+			name: "not preceding itself",
+			a:    testReservedIP("a", &pod),
+			b:    testReservedIP("a", &pod),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := precedes(tt.a, tt.b); got != tt.want {
+				t.Errorf("precedes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	assignedTo := func(spec, status ociv1alpha1.ReservedIPAssignment, state string) *ociv1alpha1.ReservedIP {
+		reservedIP := testReservedIP("a", &spec)
+		reservedIP.Status = ociv1alpha1.ReservedIPStatus{State: state, Assignment: &status}
+		return reservedIP
+	}
+	deleting := testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"})
+	deleting.DeletionTimestamp = &testCreated
+	withCondition := func(conditionType, reason string) *ociv1alpha1.ReservedIP {
+		reservedIP := testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"})
+		reservedIP.Status.Conditions = []metav1.Condition{{Type: conditionType, Status: metav1.ConditionTrue, Reason: reason}}
+		return reservedIP
+	}
+
+	tests := []struct {
+		name       string
+		reservedIP reservedIPObject
+		keys       []string
+		want       bool
+	}{
+		{
+			name:       "pod in spec",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}),
+			keys:       []string{"pod/default/pod-a", "ip/10.0.0.1"},
+			want:       true,
+		},
+		{
+			name:       "other pod",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "pod-b"}),
+			keys:       []string{"pod/default/pod-a", "ip/10.0.0.1"},
+			want:       false,
+		},
+		{
+			name:       "private IP in spec",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.0.1"}),
+			keys:       []string{"pod/default/pod-a", "ip/10.0.0.1"},
+			want:       true,
+		},
+		{
+			name:       "Lease in spec",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"}),
+			keys:       []string{"lease/default/leader"},
+			want:       true,
+		},
+		{
+			name: "private IP of the assigned pod",
+			reservedIP: assignedTo(ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"},
+				ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", PrivateIPAddress: "10.0.0.1"}, "assigned"),
+			keys: []string{"ip/10.0.0.1"},
+			want: true,
+		},
+		{
+			name: "private IP of a pod it is no longer meant for",
+			reservedIP: assignedTo(ociv1alpha1.ReservedIPAssignment{PodName: "pod-b"},
+				ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", PrivateIPAddress: "10.0.0.1"}, "assigned"),
+			keys: []string{"ip/10.0.0.1"},
+			want: false,
+		},
+		{
+			name: "private IP of a pod it is being unassigned from",
+			reservedIP: assignedTo(ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"},
+				ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", PrivateIPAddress: "10.0.0.1"}, "unassigning"),
+			keys: []string{"ip/10.0.0.1"},
+			want: false,
+		},
+		{
+			name:       "without assignment",
+			reservedIP: testReservedIP("a", nil),
+			keys:       []string{"pod/default/pod-a"},
+			want:       false,
+		},
+		{
+			name:       "being deleted",
+			reservedIP: deleting,
+			keys:       []string{"pod/default/pod-a"},
+			want:       false,
+		},
+		{
+			name:       "expired",
+			reservedIP: withCondition(ociv1alpha1.ReservedIPExpiring, "Expired"),
+			keys:       []string{"pod/default/pod-a"},
+			want:       false,
+		},
+		{
+			name:       "about to expire",
+			reservedIP: withCondition(ociv1alpha1.ReservedIPExpiring, "Expiring"),
+			keys:       []string{"pod/default/pod-a"},
+			want:       true,
+		},
+		{
+			name:       "violating a policy",
+			reservedIP: withCondition(ociv1alpha1.ReservedIPPolicyViolation, "PolicyViolation"),
+			keys:       []string{"pod/default/pod-a"},
+			want:       false,
+		},
+		{
+			name:       "over quota",
+			reservedIP: withCondition(ociv1alpha1.ReservedIPQuotaExceeded, "QuotaExceeded"),
+			keys:       []string{"pod/default/pod-a"},
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targets(tt.reservedIP, tt.keys); got != tt.want {
+				t.Errorf("targets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignmentTargetIndexKey(t *testing.T) {
+	withStatus := func(reservedIP *ociv1alpha1.ReservedIP, assignment ociv1alpha1.ReservedIPAssignment) *ociv1alpha1.ReservedIP {
+		reservedIP.Status.Assignment = &assignment
+		return reservedIP
+	}
+	cluster := func(assignment ociv1alpha1.ReservedIPAssignment) *ociv1alpha1.ClusterReservedIP {
+		return &ociv1alpha1.ClusterReservedIP{
+			ObjectMeta: metav1.ObjectMeta{Name: "a"},
+			Spec:       ociv1alpha1.ReservedIPSpec{Assignment: &assignment},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		reservedIP reservedIPObject
+		want       []string
+	}{
+		{
+			name:       "unassigned",
+			reservedIP: testReservedIP("a", nil),
+			want:       nil,
+		},
+		{
+			name:       "empty assignment",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{}),
+			want:       nil,
+		},
+		{
+			name:       "pod",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}),
+			want:       []string{"pod/default/pod-a"},
+		},
+		{
+			name:       "private IP",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.0.1"}),
+			want:       []string{"ip/10.0.0.1"},
+		},
+		{
+			name:       "Lease takes precedence over pod",
+			reservedIP: testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader", PodName: "pod-a"}),
+			want:       []string{"lease/default/leader"},
+		},
+		{
+			name:       "ClusterReservedIP uses the assignment namespace",
+			reservedIP: cluster(ociv1alpha1.ReservedIPAssignment{Namespace: "other", PodName: "pod-a"}),
+			want:       []string{"pod/other/pod-a"},
+		},
+		{
+			name: "spec and status without duplicates",
+			reservedIP: withStatus(testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}),
+				ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", PrivateIPAddress: "10.0.0.1"}),
+			want: []string{"pod/default/pod-a", "ip/10.0.0.1"},
+		},
+		{
+			name: "previous assignment in status",
+			reservedIP: withStatus(testReservedIP("a", nil),
+				ociv1alpha1.ReservedIPAssignment{PodName: "pod-b", PrivateIPAddress: "10.0.0.2"}),
+			want: []string{"pod/default/pod-b", "ip/10.0.0.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignmentTargetIndexKey(tt.reservedIP); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignmentTargetIndexKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetAssignmentConditions(t *testing.T) {
+	condition := func(conditionType string, status metav1.ConditionStatus) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status, Reason: "Test"}
+	}
+
+	tests := []struct {
+		name        string
+		conditions  []metav1.Condition
+		wantChanged bool
+	}{
+		{
+			name: "no conditions",
+		},
+		{
+			name:        "conflict",
+			conditions:  []metav1.Condition{condition(ociv1alpha1.ReservedIPConflict, metav1.ConditionTrue)},
+			wantChanged: true,
+		},
+		{
+			name: "foreign public IP and conflict",
+			conditions: []metav1.Condition{
+				condition(ociv1alpha1.ReservedIPConflict, metav1.ConditionTrue),
+				condition(ociv1alpha1.ReservedIPForeignPublicIP, metav1.ConditionTrue),
+			},
+			wantChanged: true,
+		},
+		{
+			name:       "already resolved",
+			conditions: []metav1.Condition{condition(ociv1alpha1.ReservedIPConflict, metav1.ConditionFalse)},
+		},
+		{
+			name:       "other conditions are kept",
+			conditions: []metav1.Condition{condition(ociv1alpha1.ReservedIPPaused, metav1.ConditionTrue)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := ociv1alpha1.ReservedIPStatus{Conditions: tt.conditions}
+			if got := resetAssignmentConditions(&status); got != tt.wantChanged {
+				t.Errorf("resetAssignmentConditions() = %v, want %v", got, tt.wantChanged)
+			}
+			for _, conditionType := range []string{ociv1alpha1.ReservedIPConflict, ociv1alpha1.ReservedIPForeignPublicIP} {
+				if meta.IsStatusConditionTrue(status.Conditions, conditionType) {
+					t.Errorf("condition %s is still true", conditionType)
+				}
+			}
+			if len(status.Conditions) != len(tt.conditions) {
+				t.Errorf("got %d conditions, want %d", len(status.Conditions), len(tt.conditions))
+			}
+		})
+	}
+}
+
+func TestOwnsPublicIP(t *testing.T) {
+	displayName := ocicommon.String("shared-default-a-uid")
+
+	tests := []struct {
+		name      string
+		clusterID string
+		publicIP  ocicore.PublicIp
+		want      bool
+	}{
+		{
+			name:      "tagged with the cluster ID",
+			clusterID: "cluster-a",
+			publicIP:  ocicore.PublicIp{DisplayName: displayName, FreeformTags: map[string]string{clusterTag: "cluster-a"}},
+			want:      true,
+		},
+		{
+			name:      "tagged by another cluster with the same prefix",
+			clusterID: "cluster-a",
+			publicIP:  ocicore.PublicIp{DisplayName: displayName, FreeformTags: map[string]string{clusterTag: "cluster-b"}},
+			want:      false,
+		},
+		{
+			name:      "display name with the prefix but untagged",
+			clusterID: "cluster-a",
+			publicIP:  ocicore.PublicIp{DisplayName: displayName},
+			want:      false,
+		},
+		{
+			name:      "other tags only",
+			clusterID: "cluster-a",
+			publicIP:  ocicore.PublicIp{FreeformTags: map[string]string{"team": "net"}},
+			want:      false,
+		},
+		{
+			name:     "empty cluster ID owns nothing",
+			publicIP: ocicore.PublicIp{FreeformTags: map[string]string{clusterTag: ""}},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ReservedIPReconciler{ReservedIPNamePrefix: "shared", ClusterID: tt.clusterID}
+			if got := r.ownsPublicIP(tt.publicIP); got != tt.want {
+				t.Errorf("ownsPublicIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDesiredTags(t *testing.T) {
+	r := &ReservedIPReconciler{ClusterID: "cluster-a"}
+
+	tests := []struct {
+		name     string
+		tags     *map[string]string
+		existing map[string]string
+		want     map[string]string
+	}{
+		{
+			name: "allocating without tags",
+			want: map[string]string{clusterTag: "cluster-a"},
+		},
+		{
+			name: "tags of the spec",
+			tags: &map[string]string{"team": "net"},
+			want: map[string]string{"team": "net", clusterTag: "cluster-a"},
+		},
+		{
+			name:     "spec replaces existing tags",
+			tags:     &map[string]string{"team": "net"},
+			existing: map[string]string{"team": "old", "owner": "me", clusterTag: "cluster-a"},
+			want:     map[string]string{"team": "net", clusterTag: "cluster-a"},
+		},
+		{
+			name:     "existing tags are kept without spec",
+			existing: map[string]string{"owner": "me"},
+			want:     map[string]string{"owner": "me", clusterTag: "cluster-a"},
+		},
+		{
+			name: "spec can't override the cluster tag",
+			tags: &map[string]string{clusterTag: "cluster-b"},
+			want: map[string]string{clusterTag: "cluster-a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.desiredTags(&ociv1alpha1.ReservedIPSpec{Tags: tt.tags}, tt.existing)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("desiredTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,12 +21,25 @@ import (
 	"net"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
@@ -44,10 +57,27 @@ type ReservedIPReconciler struct {
 	CompartmentID        string
 	VcnID                string
 	ReservedIPNamePrefix string
+	ClusterID            string
+	NetworkCache         *NetworkCache
+	Accounts             *OCIAccountClients
+	DryRun               bool
+	EnforcePolicies      bool
+	EnforceQuotas        bool
+	// AllowForceRelease honors the force-release annotation
+	AllowForceRelease bool
+}
+
+// reservedIPObject is implemented by ReservedIP and ClusterReservedIP, which
+// share the same spec, status and state machine.
+type reservedIPObject interface {
+	client.Object
+	GetSpec() *ociv1alpha1.ReservedIPSpec
+	GetStatus() *ociv1alpha1.ReservedIPStatus
 }
 
 // +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedips,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedips/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch
 
 func (r *ReservedIPReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("reservedIP", req.NamespacedName)
@@ -64,143 +94,193 @@ func (r *ReservedIPReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return res, err
 }
 
-func (r *ReservedIPReconciler) handleRequest(ctx context.Context, reservedIP *ociv1alpha1.ReservedIP, log logr.Logger) (ctrl.Result, error) {
-	status := &reservedIP.Status
-	spec := &reservedIP.Spec
+func (r *ReservedIPReconciler) handleRequest(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) (ctrl.Result, error) {
+	if r.isDryRun(reservedIP) {
+		dryRunReconciler := *r
+		dryRunReconciler.Client = dryRunClient{r.Client}
+		r = &dryRunReconciler
+	}
 
-	if reservedIP.ObjectMeta.DeletionTimestamp.IsZero() {
-		if !containsString(reservedIP.ObjectMeta.Finalizers, finalizerName) {
-			// add finalizer, set initial state
-			reservedIP.ObjectMeta.Finalizers = append(reservedIP.ObjectMeta.Finalizers, finalizerName)
-			return ctrl.Result{}, r.Update(ctx, reservedIP)
+	if !reservedIP.GetDeletionTimestamp().IsZero() &&
+		reservedIP.GetAnnotations()[ociv1alpha1.ForceReleaseAnnotation] == "true" &&
+		containsString(reservedIP.GetFinalizers(), finalizerName) {
+		if r.AllowForceRelease {
+			return ctrl.Result{}, r.abandonReservedIP(ctx, reservedIP, log)
 		}
+		log.Info("ignoring force release as it is disabled")
+		r.Recorder.Event(reservedIP, "Warning", "ForceReleaseDisabled", "The force-release annotation is ignored as the operator was started without -enable-force-release")
+	}
 
-		if status.State == "" {
-			status.State = "allocating"
-			if err := r.Status().Update(ctx, reservedIP); err != nil {
-				return ctrl.Result{}, err
-			}
+	accountReconciler, err := r.forAccount(ctx, reservedIP)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return accountReconciler.reconcileReservedIP(ctx, reservedIP, log)
+}
+
+// reconcileReservedIP reconciles the ReservedIP with the OCI clients of its
+// account.
+func (r *ReservedIPReconciler) reconcileReservedIP(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) (ctrl.Result, error) {
+	status := reservedIP.GetStatus()
+	spec := reservedIP.GetSpec()
+
+	if isPaused(reservedIP) && reservedIP.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, r.pause(ctx, reservedIP, log)
+	}
+	if meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPPaused) {
+		if err := r.resume(ctx, reservedIP, log); err != nil {
+			return ctrl.Result{}, err
 		}
+	}
 
-		if status.State == "allocating" {
-			if err := r.allocateReservedIP(ctx, reservedIP, log); err != nil {
-				return ctrl.Result{}, err
-			}
-			r.Recorder.Event(reservedIP, "Normal", "Allocating", "Reserved IP allocated")
+	var requeueAfter time.Duration
+	if reservedIP.GetDeletionTimestamp().IsZero() {
+		var deleted bool
+		var err error
+		requeueAfter, deleted, err = r.reconcileExpiry(ctx, reservedIP, log)
+		if deleted || err != nil {
+			return ctrl.Result{}, err
 		}
+	}
 
-		addr, err := r.VNC.GetPublicIp(ctx, ocicore.GetPublicIpRequest{
-			PublicIpId: &status.OCID,
-		})
-		if err != nil {
-			if strings.Contains(err.Error(), "NotAuthorizedOrNotFound") {
-				log.Info("allocation ID not found; assuming EIP was released; not doing anything", "ocid", reservedIP.Status.OCID)
-			}
+	var violation bool
+	if r.EnforcePolicies && reservedIP.GetDeletionTimestamp().IsZero() {
+		var err error
+		if violation, err = r.enforcePolicies(ctx, reservedIP, log); err != nil {
 			return ctrl.Result{}, err
 		}
-		log = log.WithValues("publicIPID", addr.Id)
+	}
 
-		if err := r.reconcileTags(ctx, reservedIP, addr.FreeformTags); err != nil {
+	if spec.Assignment == nil {
+		if err := r.clearAssignmentConditions(ctx, reservedIP); err != nil {
 			return ctrl.Result{}, err
 		}
+	}
 
-		if status.State == "allocated" {
-			if spec.Assignment != nil {
-				if spec.Assignment.PodName != "" || spec.Assignment.PrivateIPAddress != "" {
-					status.State = "assigning"
-					if err := r.Status().Update(ctx, reservedIP); err != nil {
-						return ctrl.Result{}, err
-					}
-					r.Recorder.Event(reservedIP, "Normal", "Assigning", "Reserved IP assigned")
-				}
-			}
+	var overQuota bool
+	if r.EnforceQuotas && reservedIP.GetDeletionTimestamp().IsZero() {
+		var err error
+		if overQuota, err = r.enforceQuotas(ctx, reservedIP, log); err != nil {
+			return ctrl.Result{}, err
 		}
+	}
 
-		if status.State == "assigned" {
-			changed := false
-			if spec.Assignment == nil {
-				// assignment was removed
-				status.State = "unassigning"
-				changed = true
-			} else if !status.Assignment.MatchesSpec(*spec.Assignment) || addr.AssignedEntityId == nil {
-				// assignment was changed (in spec or in EC2)
-				status.State = "reassigning"
-				changed = true
-			}
+	observed, err := r.observe(ctx, reservedIP, log)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	observed.PolicyViolation = violation
+	observed.QuotaExceeded = overQuota
+	observed.Expired = isExpired(status)
 
-			if changed {
-				if err := r.Status().Update(ctx, reservedIP); err != nil {
-					return ctrl.Result{}, err
-				}
-			}
+	p := transition(*spec, *status, observed)
+	if r.isDryRun(reservedIP) {
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.dryRun(ctx, reservedIP, p, log)
+	}
+	if p.State != "" {
+		log.Info("state changed", "previousState", status.State, "state", p.State)
+		patch := mergeFrom(reservedIP)
+		status.State = p.State
+		if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+			return ctrl.Result{}, err
 		}
-
-		if status.State == "assigning" {
-			if spec.Assignment == nil {
-				// assignment was removed before EIP was actually assigned
-				status.State = "allocated"
-				if err := r.Status().Update(ctx, reservedIP); err != nil {
-					return ctrl.Result{}, err
-				}
-			}
+		if p.EventReason != "" {
+			r.Recorder.Event(reservedIP, "Normal", p.EventReason, p.EventMessage)
 		}
+	}
 
-		if status.State == "assigning" || status.State == "reassigning" {
-			if spec.Assignment != nil {
-				if spec.Assignment.PodName != "" || spec.Assignment.PrivateIPAddress != "" {
-					return ctrl.Result{}, r.assignReservedIP(ctx, reservedIP, log)
-				}
-			}
+	for _, a := range p.Actions {
+		if err := r.execute(ctx, reservedIP, a, observed, log); err != nil {
+			return ctrl.Result{}, err
 		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
 
-		if status.State == "unassigning" {
-			return ctrl.Result{}, r.unassignReservedIP(ctx, reservedIP, log)
-		}
-	} else {
-		// EIP object is being deleted
-		if containsString(reservedIP.ObjectMeta.Finalizers, finalizerName) {
-			if status.OCID != "" {
-				if status.State != "releasing" {
-					status.State = "releasing"
-					if err := r.Status().Update(ctx, reservedIP); err != nil {
-						return ctrl.Result{}, err
-					}
-				}
+// observe collects what transition needs to know about the ReservedIP from
+// Kubernetes and OCI.
+func (r *ReservedIPReconciler) observe(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) (observedState, error) {
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	observed := observedState{
+		Deleting:     !reservedIP.GetDeletionTimestamp().IsZero(),
+		HasFinalizer: containsString(reservedIP.GetFinalizers(), finalizerName),
+	}
+	if observed.Deleting || !observed.HasFinalizer || status.State == "" || status.State == "allocating" {
+		return observed, nil
+	}
 
-				if status.State == "releasing" {
-					if err := r.releaseReservedIP(ctx, reservedIP, log); err != nil {
-						return ctrl.Result{}, err
-					}
-				}
-			}
+	addr, err := r.VNC.GetPublicIp(ctx, ocicore.GetPublicIpRequest{
+		PublicIpId: &status.OCID,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "NotAuthorizedOrNotFound") {
+			log.Info("allocation ID not found; assuming EIP was released; not doing anything", "ocid", status.OCID)
+		}
+		return observed, err
+	}
+	observed.PublicIP = &addr.PublicIp
+	// public IPs allocated before the cluster tag was introduced get it now
+	observed.MissingClusterTag = addr.FreeformTags[clusterTag] != r.ClusterID
 
-			// remove finalizer, allow k8s to remove the resource
-			reservedIP.ObjectMeta.Finalizers = removeString(reservedIP.ObjectMeta.Finalizers, finalizerName)
-			return ctrl.Result{}, r.Update(ctx, reservedIP)
+	if status.State == "assigned" && spec.Assignment != nil && spec.Assignment.LeaseName != "" {
+		observed.LeaseHolder, err = r.getLeaseHolderPod(ctx, reservedIP)
+		if err != nil {
+			return observed, err
 		}
 	}
+	return observed, nil
+}
 
-	return ctrl.Result{}, nil
+// execute runs a single action of a plan
+func (r *ReservedIPReconciler) execute(ctx context.Context, reservedIP reservedIPObject, a action, observed observedState, log logr.Logger) error {
+	switch a {
+	case actionAddFinalizer:
+		return addFinalizer(ctx, r.Client, reservedIP)
+	case actionAllocate:
+		if err := r.allocateReservedIP(ctx, reservedIP, log); err != nil {
+			return err
+		}
+		r.Recorder.Event(reservedIP, "Normal", "Allocating", "Reserved IP allocated")
+		return nil
+	case actionUpdateTags:
+		return r.reconcileTags(ctx, reservedIP, observed.PublicIP.FreeformTags, log)
+	case actionAssign:
+		return r.assignReservedIP(ctx, reservedIP, log)
+	case actionUnassign:
+		return r.unassignReservedIP(ctx, reservedIP, log)
+	case actionRelease:
+		return r.releaseReservedIP(ctx, reservedIP, log)
+	case actionRemoveFinalizer:
+		// allow k8s to remove the resource
+		return removeFinalizer(ctx, r.Client, reservedIP)
+	default:
+		return fmt.Errorf("unknown action %s", a)
+	}
 }
 
-func (r *ReservedIPReconciler) allocateReservedIP(ctx context.Context, reservedIP *ociv1alpha1.ReservedIP, log logr.Logger) error {
+func (r *ReservedIPReconciler) allocateReservedIP(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
 	log.Info("allocating")
 
-	displayName := fmt.Sprintf("%s-%s-%s-%s", r.ReservedIPNamePrefix, reservedIP.Namespace, reservedIP.Name, reservedIP.UID)
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	displayName := fmt.Sprintf("%s-%s-%s-%s", r.ReservedIPNamePrefix, reservedIP.GetNamespace(), reservedIP.GetName(), reservedIP.GetUID())
+	compartmentID := r.CompartmentID
+	if spec.CompartmentID != "" {
+		compartmentID = spec.CompartmentID
+	}
 
 	input := ocicore.CreatePublicIpRequest{
 		CreatePublicIpDetails: ocicore.CreatePublicIpDetails{
-			CompartmentId: ocicommon.String(r.CompartmentID),
+			CompartmentId: ocicommon.String(compartmentID),
 			DisplayName:   ocicommon.String(displayName),
 			Lifetime:      ocicore.CreatePublicIpDetailsLifetimeReserved,
 		},
-		OpcRetryToken: ocicommon.String(string(reservedIP.UID)),
+		OpcRetryToken: ocicommon.String(string(reservedIP.GetUID())),
 	}
-	if reservedIP.Spec.Tags != nil {
-		input.FreeformTags = *reservedIP.Spec.Tags
-	}
-	if reservedIP.Spec.PublicIPPoolID != "" {
-		input.PublicIpPoolId = ocicommon.String(reservedIP.Spec.PublicIPPoolID)
+	input.FreeformTags = r.desiredTags(spec, nil)
+	if spec.PublicIPPoolID != "" {
+		input.PublicIpPoolId = ocicommon.String(spec.PublicIPPoolID)
 	}
 
 	resp, err := r.VNC.CreatePublicIp(ctx, input)
@@ -208,23 +288,40 @@ func (r *ReservedIPReconciler) allocateReservedIP(ctx context.Context, reservedI
 		return err
 	}
 
-	reservedIP.Status.State = "allocated"
-	reservedIP.Status.OCID = *resp.Id
-	reservedIP.Status.PublicIPAddress = *resp.IpAddress
-	r.Log.Info("allocated", "ocid", reservedIP.Status.OCID)
-	if err := r.Status().Update(ctx, reservedIP); err != nil {
+	patch := mergeFrom(reservedIP)
+	status.State = "allocated"
+	status.OCID = *resp.Id
+	status.PublicIPAddress = *resp.IpAddress
+	r.Log.Info("allocated", "ocid", status.OCID)
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
 		return err
 	}
 
-	return r.reconcileTags(ctx, reservedIP, resp.FreeformTags)
+	return r.reconcileTags(ctx, reservedIP, resp.FreeformTags, log)
+}
+
+// desiredTags returns the freeform tags the public IP should have: those of
+// the spec, or the existing ones if the spec has none, plus the cluster tag.
+func (r *ReservedIPReconciler) desiredTags(spec *ociv1alpha1.ReservedIPSpec, existingTags map[string]string) map[string]string {
+	if spec.Tags != nil {
+		existingTags = *spec.Tags
+	}
+	tags := map[string]string{clusterTag: r.ClusterID}
+	for key, value := range existingTags {
+		if key != clusterTag {
+			tags[key] = value
+		}
+	}
+	return tags
 }
 
-func (r *ReservedIPReconciler) reconcileTags(ctx context.Context, reservedIP *ociv1alpha1.ReservedIP, existingTags map[string]string) error {
-	if reservedIP.Spec.Tags != nil && !reflect.DeepEqual(*reservedIP.Spec.Tags, existingTags) {
+func (r *ReservedIPReconciler) reconcileTags(ctx context.Context, reservedIP reservedIPObject, existingTags map[string]string, log logr.Logger) error {
+	tags := r.desiredTags(reservedIP.GetSpec(), existingTags)
+	if !reflect.DeepEqual(tags, existingTags) {
 		_, err := r.VNC.UpdatePublicIp(ctx, ocicore.UpdatePublicIpRequest{
-			PublicIpId: ocicommon.String(reservedIP.Status.OCID),
+			PublicIpId: ocicommon.String(reservedIP.GetStatus().OCID),
 			UpdatePublicIpDetails: ocicore.UpdatePublicIpDetails{
-				FreeformTags: *reservedIP.Spec.Tags,
+				FreeformTags: tags,
 			},
 		})
 		return err
@@ -232,13 +329,13 @@ func (r *ReservedIPReconciler) reconcileTags(ctx context.Context, reservedIP *oc
 	return nil
 }
 
-func (r *ReservedIPReconciler) assignEphemeralIP(ctx context.Context, reservedIP *ociv1alpha1.ReservedIP, log logr.Logger) error {
+func (r *ReservedIPReconciler) assignEphemeralIP(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
 	log.Info("ephemeral IP was unassigned for assigning ReservedIP; assigning a new ephemeral IP")
 
 	input := ocicore.CreatePublicIpRequest{
 		CreatePublicIpDetails: ocicore.CreatePublicIpDetails{
 			CompartmentId: ocicommon.String(r.CompartmentID),
-			PrivateIpId:   ocicommon.String(reservedIP.Status.PrivateIPAddressID),
+			PrivateIpId:   ocicommon.String(reservedIP.GetStatus().PrivateIPAddressID),
 			Lifetime:      ocicore.CreatePublicIpDetailsLifetimeEphemeral,
 		},
 	}
@@ -246,22 +343,23 @@ func (r *ReservedIPReconciler) assignEphemeralIP(ctx context.Context, reservedIP
 		return err
 	}
 
-	reservedIP.Status.EphemeralIPWasUnassigned = false
-	if err := r.Status().Update(ctx, reservedIP); err != nil {
+	patch := mergeFrom(reservedIP)
+	reservedIP.GetStatus().EphemeralIPWasUnassigned = false
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (r *ReservedIPReconciler) releaseReservedIP(ctx context.Context, eip *ociv1alpha1.ReservedIP, log logr.Logger) error {
+func (r *ReservedIPReconciler) releaseReservedIP(ctx context.Context, eip reservedIPObject, log logr.Logger) error {
 	log.Info("releasing")
 
 	if _, err := r.VNC.DeletePublicIp(ctx, ocicore.DeletePublicIpRequest{
-		PublicIpId: ocicommon.String(eip.Status.OCID),
+		PublicIpId: ocicommon.String(eip.GetStatus().OCID),
 	}); err != nil {
 		if strings.Contains(err.Error(), "NotAuthorizedOrNotFound") {
-			log.Info("ReservedIP not found; assuming ReservedIP is already released", "OCID", eip.Status.OCID)
+			log.Info("ReservedIP not found; assuming ReservedIP is already released", "OCID", eip.GetStatus().OCID)
 		} else {
 			return err
 		}
@@ -269,13 +367,25 @@ func (r *ReservedIPReconciler) releaseReservedIP(ctx context.Context, eip *ociv1
 
 	log.Info("released")
 
-	if eip.Status.EphemeralIPWasUnassigned {
+	if eip.GetStatus().EphemeralIPWasUnassigned {
 		return r.assignEphemeralIP(ctx, eip, log)
 	}
 
 	return nil
 }
 
+// abandonReservedIP removes the finalizer without releasing the public IP in
+// OCI, leaving it to be deleted manually.
+func (r *ReservedIPReconciler) abandonReservedIP(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
+	status := reservedIP.GetStatus()
+	if status.OCID != "" {
+		log.Info("force release: abandoning public IP", "ocid", status.OCID, "publicIP", status.PublicIPAddress)
+		r.Recorder.Event(reservedIP, "Warning", "Abandoned", fmt.Sprintf("Force released without releasing public IP %s (%s) in OCI; delete it manually", status.OCID, status.PublicIPAddress))
+	}
+
+	return removeFinalizer(ctx, r.Client, reservedIP)
+}
+
 func (r *ReservedIPReconciler) getPodPrivateIP(ctx context.Context, namespace, podName string) (string, error) {
 	pod := &corev1.Pod{}
 	if err := r.Get(ctx, client.ObjectKey{
@@ -288,51 +398,205 @@ func (r *ReservedIPReconciler) getPodPrivateIP(ctx context.Context, namespace, p
 	return pod.Status.PodIP, nil
 }
 
+// podNamespace returns the namespace of the pod the ReservedIP should be
+// assigned to. ReservedIPs can only be assigned to pods in their own namespace,
+// ClusterReservedIPs need spec.assignment.namespace.
+func podNamespace(reservedIP reservedIPObject) (string, error) {
+	assignment := reservedIP.GetSpec().Assignment
+	if reservedIP.GetNamespace() == "" {
+		if assignment.Namespace == "" {
+			return "", fmt.Errorf("spec.assignment.namespace needs to be defined when assigning a ClusterReservedIP to a pod")
+		}
+		return assignment.Namespace, nil
+	}
+	if assignment.Namespace != "" && assignment.Namespace != reservedIP.GetNamespace() {
+		return "", fmt.Errorf("ReservedIPs can only be assigned to pods in their own namespace")
+	}
+	return reservedIP.GetNamespace(), nil
+}
+
+// getLeaseHolderPod returns the name of the pod holding the Lease given in
+// spec.assignment.leaseName. Besides plain pod names, it understands holder
+// identities of the form <pod name>_<id> as used by client-go leader election.
+func (r *ReservedIPReconciler) getLeaseHolderPod(ctx context.Context, reservedIP reservedIPObject) (string, error) {
+	namespace, err := podNamespace(reservedIP)
+	if err != nil {
+		return "", err
+	}
+
+	lease := &coordinationv1.Lease{}
+	if err := r.Get(ctx, client.ObjectKey{
+		Namespace: namespace,
+		Name:      reservedIP.GetSpec().Assignment.LeaseName,
+	}, lease); err != nil {
+		return "", err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		return "", nil
+	}
+
+	holder := *lease.Spec.HolderIdentity
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: holder}, pod)
+	if err == nil {
+		return holder, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+	if i := strings.LastIndex(holder, "_"); i > 0 {
+		return holder[:i], nil
+	}
+	return holder, nil
+}
+
+// forAccount returns a reconciler talking to the OCI account the ReservedIP
+// references, or r itself if it doesn't reference one. ReservedIPs may only
+// use accounts whose namespaceSelector selects their namespace.
+func (r *ReservedIPReconciler) forAccount(ctx context.Context, reservedIP reservedIPObject) (*ReservedIPReconciler, error) {
+	name := reservedIP.GetSpec().AccountName
+	if name == "" {
+		return r, nil
+	}
+	if r.Accounts == nil {
+		return nil, fmt.Errorf("OCIAccounts are not supported by this operator")
+	}
+
+	account, err := getAccount(ctx, r.Client, name, reservedIP.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	accountClient, err := r.Accounts.get(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	accountReconciler := *r
+	accountReconciler.VNC = accountClient.vnc
+	accountReconciler.NetworkCache = accountClient.networkCache
+	accountReconciler.CompartmentID = account.Spec.CompartmentID
+	accountReconciler.VcnID = account.Spec.VcnID
+	return &accountReconciler, nil
+}
+
 func (r *ReservedIPReconciler) getPrivateIPID(ctx context.Context, privateIP string) (string, error) {
-	subnets, err := r.VNC.ListSubnets(ctx, ocicore.ListSubnetsRequest{
-		CompartmentId: ocicommon.String(r.CompartmentID),
-		VcnId:         ocicommon.String(r.VcnID),
-	})
+	if id, ok := r.NetworkCache.getPrivateIPID(privateIP); ok {
+		return id, nil
+	}
+
+	ip := net.ParseIP(privateIP)
+	subnets, err := r.getSubnets(ctx, ip)
 	if err != nil {
 		return "", err
 	}
 
-	for _, subnet := range subnets.Items {
-		_, cidr, err := net.ParseCIDR(*subnet.CidrBlock)
-		if err != nil {
-			return "", err
-		}
-		if !cidr.Contains(net.ParseIP(privateIP)) {
+	for _, subnet := range subnets {
+		if !subnet.cidr.Contains(ip) {
 			continue
 		}
 
 		resp, err := r.VNC.ListPrivateIps(ctx, ocicore.ListPrivateIpsRequest{
 			IpAddress: ocicommon.String(privateIP),
-			SubnetId:  subnet.Id,
+			SubnetId:  ocicommon.String(subnet.id),
 		})
 		if err != nil {
 			return "", err
 		}
 		if len(resp.Items) == 1 {
+			r.NetworkCache.setPrivateIPID(privateIP, *resp.Items[0].Id)
 			return *resp.Items[0].Id, nil
 		}
 	}
 
+	// the subnet may have been recreated with the same CIDR
+	r.NetworkCache.invalidateSubnets()
 	return "", fmt.Errorf("Private IP %s not found in VCN %s", privateIP, r.VcnID)
 }
 
-func (r *ReservedIPReconciler) assignReservedIP(ctx context.Context, reservedIP *ociv1alpha1.ReservedIP, log logr.Logger) error {
-	if (reservedIP.Spec.Assignment.PodName == "") == (reservedIP.Spec.Assignment.PrivateIPAddress == "") {
-		return fmt.Errorf("exactly one of spec.assignment.{podName,privateIPAddress} needs to be defined")
+// getSubnets returns the subnets of the VCN, from the cache if possible. If
+// the cached subnets don't contain ip, they are fetched again in case the
+// subnet was created recently.
+func (r *ReservedIPReconciler) getSubnets(ctx context.Context, ip net.IP) ([]cachedSubnet, error) {
+	if subnets, ok := r.NetworkCache.getSubnets(); ok {
+		for _, subnet := range subnets {
+			if subnet.cidr.Contains(ip) {
+				return subnets, nil
+			}
+		}
+	}
+
+	var subnets []cachedSubnet
+	req := ocicore.ListSubnetsRequest{
+		CompartmentId: ocicommon.String(r.CompartmentID),
+		VcnId:         ocicommon.String(r.VcnID),
+	}
+	for {
+		resp, err := r.VNC.ListSubnets(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range resp.Items {
+			_, cidr, err := net.ParseCIDR(*subnet.CidrBlock)
+			if err != nil {
+				return nil, err
+			}
+			subnets = append(subnets, cachedSubnet{id: *subnet.Id, cidr: cidr})
+		}
+		if resp.OpcNextPage == nil {
+			break
+		}
+		req.Page = resp.OpcNextPage
+	}
+
+	r.NetworkCache.setSubnets(subnets)
+	return subnets, nil
+}
+
+func (r *ReservedIPReconciler) assignReservedIP(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	targets := 0
+	for _, target := range []string{spec.Assignment.PodName, spec.Assignment.PrivateIPAddress, spec.Assignment.LeaseName} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("exactly one of spec.assignment.{podName,privateIPAddress,leaseName} needs to be defined")
 	}
 
 	var err error
-	privateIP := reservedIP.Spec.Assignment.PrivateIPAddress
-	if reservedIP.Spec.Assignment.PodName != "" {
-		privateIP, err = r.getPodPrivateIP(ctx, reservedIP.Namespace, reservedIP.Spec.Assignment.PodName)
+	privateIP := spec.Assignment.PrivateIPAddress
+	podName := spec.Assignment.PodName
+	if spec.Assignment.LeaseName != "" {
+		podName, err = r.getLeaseHolderPod(ctx, reservedIP)
 		if err != nil {
 			return err
 		}
+		if podName == "" {
+			return fmt.Errorf("Lease %s is not held by any pod", spec.Assignment.LeaseName)
+		}
+	}
+	keys := specTargetKeys(reservedIP)
+	if podName != "" {
+		namespace, err := podNamespace(reservedIP)
+		if err != nil {
+			return err
+		}
+		privateIP, err = r.getPodPrivateIP(ctx, namespace, podName)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, "pod/"+namespace+"/"+podName)
+	}
+	keys = append(keys, "ip/"+privateIP)
+
+	if conflict, err := r.checkConflict(ctx, reservedIP, keys, log); conflict || err != nil {
+		return err
+	}
+	if status.Assignment != nil && status.Assignment.PrivateIPAddress != "" && status.Assignment.PrivateIPAddress != privateIP {
+		// the pod IP changed; the private IP object of the old one is likely gone
+		r.NetworkCache.invalidatePrivateIP(status.Assignment.PrivateIPAddress)
 	}
 
 	privateIPID, err := r.getPrivateIPID(ctx, privateIP)
@@ -340,6 +604,7 @@ func (r *ReservedIPReconciler) assignReservedIP(ctx context.Context, reservedIP
 		return err
 	}
 
+	alreadyAssigned := false
 	publicIP, err := r.VNC.GetPublicIpByPrivateIpId(ctx, ocicore.GetPublicIpByPrivateIpIdRequest{
 		GetPublicIpByPrivateIpIdDetails: ocicore.GetPublicIpByPrivateIpIdDetails{
 			PrivateIpId: &privateIPID,
@@ -349,19 +614,19 @@ func (r *ReservedIPReconciler) assignReservedIP(ctx context.Context, reservedIP
 		if !strings.Contains(err.Error(), "NotAuthorizedOrNotFound") {
 			return err
 		} // no public IP is assigned to the private IP -> just continue
+	} else if publicIP.Id != nil && *publicIP.Id == status.OCID {
+		// correct public IP already assigned
+		alreadyAssigned = true
 	} else {
-		if publicIP.Id == &reservedIP.Status.OCID { // correct public IP already assigned
-			return nil
-		}
-
 		if publicIP.Lifetime == ocicore.PublicIpLifetimeEphemeral {
 			log.Info("deleting emphemeral public IP previously assigned to private IP",
-				"podName", reservedIP.Spec.Assignment.PodName,
+				"podName", spec.Assignment.PodName,
 				"privateIP", privateIP,
 				"privateIPID", privateIPID,
 				"previousPublicIPID", *publicIP.Id)
-			reservedIP.Status.EphemeralIPWasUnassigned = true
-			if err := r.Status().Update(ctx, reservedIP); err != nil {
+			patch := mergeFrom(reservedIP)
+			status.EphemeralIPWasUnassigned = true
+			if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
 				return err
 			}
 			_, err = r.VNC.DeletePublicIp(ctx, ocicore.DeletePublicIpRequest{
@@ -370,9 +635,11 @@ func (r *ReservedIPReconciler) assignReservedIP(ctx context.Context, reservedIP
 			if err != nil {
 				return err
 			}
+		} else if !spec.Assignment.AllowTakeover && !r.ownsPublicIP(publicIP.PublicIp) {
+			return r.refuseTakeover(ctx, reservedIP, publicIP.PublicIp, privateIP, log)
 		} else {
 			log.Info("unassigning reserved IP previously assigned to private IP",
-				"podName", reservedIP.Spec.Assignment.PodName,
+				"podName", spec.Assignment.PodName,
 				"privateIP", privateIP,
 				"privateIPID", privateIPID,
 				"previousPublicIPID", *publicIP.Id)
@@ -388,36 +655,52 @@ func (r *ReservedIPReconciler) assignReservedIP(ctx context.Context, reservedIP
 		}
 	}
 
-	log.Info("assigning public IP to private IP", "podName", reservedIP.Spec.Assignment.PodName, "privateIP", privateIP, "privateIPID", privateIPID)
+	if !alreadyAssigned {
+		log.Info("assigning public IP to private IP", "podName", spec.Assignment.PodName, "privateIP", privateIP, "privateIPID", privateIPID)
 
-	_, err = r.VNC.UpdatePublicIp(ctx, ocicore.UpdatePublicIpRequest{
-		PublicIpId: ocicommon.String(reservedIP.Status.OCID),
-		UpdatePublicIpDetails: ocicore.UpdatePublicIpDetails{
-			PrivateIpId: &privateIPID,
-		},
-	})
-	if err != nil {
-		return err
-	}
+		_, err = r.VNC.UpdatePublicIp(ctx, ocicore.UpdatePublicIpRequest{
+			PublicIpId: ocicommon.String(status.OCID),
+			UpdatePublicIpDetails: ocicore.UpdatePublicIpDetails{
+				PrivateIpId: &privateIPID,
+			},
+		})
+		if err != nil {
+			// the cached private IP might not exist anymore -> look it up again next time
+			r.NetworkCache.invalidatePrivateIP(privateIP)
+			return err
+		}
 
-	log.Info("assigned")
+		log.Info("assigned")
+	}
 
-	reservedIP.Status.State = "assigned"
-	reservedIP.Status.Assignment = reservedIP.Spec.Assignment
-	reservedIP.Status.Assignment.PrivateIPAddress = privateIP
-	reservedIP.Status.PrivateIPAddressID = privateIPID
-	if err := r.Status().Update(ctx, reservedIP); err != nil {
+	patch := mergeFrom(reservedIP)
+	if meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPForeignPublicIP) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ociv1alpha1.ReservedIPForeignPublicIP,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Assigned",
+			Message: "The public IP is assigned to the target private IP",
+		})
+	}
+	status.State = "assigned"
+	status.Assignment = spec.Assignment.DeepCopy()
+	status.Assignment.PodName = podName
+	status.Assignment.PrivateIPAddress = privateIP
+	status.PrivateIPAddressID = privateIPID
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (r *ReservedIPReconciler) unassignReservedIP(ctx context.Context, reservedIP *ociv1alpha1.ReservedIP, log logr.Logger) error {
+func (r *ReservedIPReconciler) unassignReservedIP(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
 	log.Info("unassigning")
 
+	status := reservedIP.GetStatus()
+
 	_, err := r.VNC.UpdatePublicIp(ctx, ocicore.UpdatePublicIpRequest{
-		PublicIpId: ocicommon.String(reservedIP.Status.OCID),
+		PublicIpId: ocicommon.String(status.OCID),
 		UpdatePublicIpDetails: ocicore.UpdatePublicIpDetails{
 			PrivateIpId: ocicommon.String(""),
 		},
@@ -427,25 +710,111 @@ func (r *ReservedIPReconciler) unassignReservedIP(ctx context.Context, reservedI
 	}
 
 	log.Info("unassigned")
+	if status.Assignment != nil && status.Assignment.PrivateIPAddress != "" {
+		// the pod may be gone, and its IP reused by a new private IP object
+		r.NetworkCache.invalidatePrivateIP(status.Assignment.PrivateIPAddress)
+	}
 
-	if reservedIP.Status.EphemeralIPWasUnassigned {
+	if status.EphemeralIPWasUnassigned {
 		if err := r.assignEphemeralIP(ctx, reservedIP, log); err != nil {
 			return err
 		}
 	}
 
-	reservedIP.Status.State = "allocated"
-	reservedIP.Status.Assignment = nil
-	reservedIP.Status.PrivateIPAddressID = ""
-	if err := r.Status().Update(ctx, reservedIP); err != nil {
+	patch := mergeFrom(reservedIP)
+	status.State = "allocated"
+	status.Assignment = nil
+	status.PrivateIPAddressID = ""
+	resetAssignmentConditions(status)
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// leaseIndexKey returns the namespace/name of the Lease the ReservedIP follows,
+// for indexing under leaseField.
+func leaseIndexKey(obj client.Object) []string {
+	reservedIP := obj.(reservedIPObject)
+	assignment := reservedIP.GetSpec().Assignment
+	if assignment == nil || assignment.LeaseName == "" {
+		return nil
+	}
+	namespace := reservedIP.GetNamespace()
+	if namespace == "" {
+		namespace = assignment.Namespace
+	}
+	return []string{namespace + "/" + assignment.LeaseName}
+}
+
+// enqueueForLease enqueues all objects of the given list type following a
+// Lease.
+func enqueueForLease(c client.Client, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		list := list.DeepCopyObject().(client.ObjectList)
+		if err := c.List(context.Background(), list, client.MatchingFields{leaseField: obj.GetNamespace() + "/" + obj.GetName()}); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		_ = meta.EachListItem(list, func(item runtime.Object) error {
+			o := item.(client.Object)
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}})
+			return nil
+		})
+		return requests
+	})
+}
+
+// leasePredicate drops events of Leases that can't change the holder of a
+// followed Lease: node heartbeats, and renewals by the same holder, which
+// happen every few seconds for every leader election in the cluster.
+func leasePredicate() predicate.Predicate {
+	return predicate.And(
+		predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetNamespace() != corev1.NamespaceNodeLease
+		}),
+		predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				oldLease, ok := e.ObjectOld.(*coordinationv1.Lease)
+				newLease, ok2 := e.ObjectNew.(*coordinationv1.Lease)
+				if !ok || !ok2 {
+					return true
+				}
+				return !reflect.DeepEqual(oldLease.Spec.HolderIdentity, newLease.Spec.HolderIdentity)
+			},
+		},
+	)
+}
+
 func (r *ReservedIPReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ReservedIP{}, leaseField, leaseIndexKey); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ReservedIP{}, assignmentTargetField, assignmentTargetIndexKey); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ReservedIP{}, accountNameField, accountNameIndexKey); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&ociv1alpha1.ReservedIP{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &coordinationv1.Lease{}}, enqueueForLease(mgr.GetClient(), &ociv1alpha1.ReservedIPList{}), builder.WithPredicates(leasePredicate())).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIP{}}, enqueueConflicting(mgr.GetClient(), &ociv1alpha1.ReservedIPList{})).
+		Watches(&source.Kind{Type: &ociv1alpha1.ClusterReservedIP{}}, enqueueConflicting(mgr.GetClient(), &ociv1alpha1.ReservedIPList{}))
+	if r.EnforcePolicies {
+		b = b.Watches(&source.Kind{Type: &ociv1alpha1.ReservedIPPolicy{}}, enqueueForPolicy(mgr.GetClient()))
+	}
+	if r.EnforceQuotas {
+		b = b.Watches(&source.Kind{Type: &ociv1alpha1.ReservedIPQuota{}}, enqueueOverQuota(mgr.GetClient()))
+	}
+	if r.EnforcePolicies || r.Accounts != nil {
+		b = b.Watches(&source.Kind{Type: &corev1.Namespace{}}, enqueueForNamespace(mgr.GetClient()), builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	if r.Accounts != nil {
+		b = b.Watches(&source.Kind{Type: &ociv1alpha1.OCIAccount{}}, r.Accounts.enqueueForAccount(mgr.GetClient(), &ociv1alpha1.ReservedIPList{})).
+			Watches(source.NewKindWithCache(&corev1.Secret{}, r.Accounts.Secrets), r.Accounts.enqueueForSecret(mgr.GetClient(), &ociv1alpha1.ReservedIPList{}))
+	}
+	return b.Complete(r)
 }
@@ -0,0 +1,204 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// testLease returns a Lease in namespace held by holder, if it isn't nil
+func testLease(namespace string, holder *string) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "leader"},
+		Spec:       coordinationv1.LeaseSpec{HolderIdentity: holder},
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestGetLeaseHolderPod(t *testing.T) {
+	pod := func(name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	}
+
+	tests := []struct {
+		name    string
+		objs    []client.Object
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Lease not found",
+			wantErr: true,
+		},
+		{
+			name: "Lease not held",
+			objs: []client.Object{testLease("default", nil)},
+		},
+		{
+			name: "Lease released",
+			objs: []client.Object{testLease("default", stringPtr(""))},
+		},
+		{
+			name: "held by a pod",
+			objs: []client.Object{testLease("default", stringPtr("web-0")), pod("web-0")},
+			want: "web-0",
+		},
+		{
+			name: "held by a pod with a leader election ID",
+			objs: []client.Object{testLease("default", stringPtr("web-5d8f7c_3f2a9b1e-7c1d-4c43-9a0e-1b2c3d4e5f60")), pod("web-5d8f7c")},
+			want: "web-5d8f7c",
+		},
+		{
+			name: "pod name containing an underscore",
+			objs: []client.Object{testLease("default", stringPtr("web_0")), pod("web_0")},
+			want: "web_0",
+		},
+		{
+			name: "holder without a pod",
+			objs: []client.Object{testLease("default", stringPtr("web-0"))},
+			want: "web-0",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := coordinationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ReservedIPReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()}
+			reservedIP := testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"})
+			got, err := r.getLeaseHolderPod(context.Background(), reservedIP)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getLeaseHolderPod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getLeaseHolderPod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaseIndexKey(t *testing.T) {
+	clusterReservedIP := &ociv1alpha1.ClusterReservedIP{
+		Spec: ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader", Namespace: "kube-system"}},
+	}
+
+	tests := []struct {
+		name string
+		obj  client.Object
+		want []string
+	}{
+		{
+			name: "without assignment",
+			obj:  testReservedIP("a", nil),
+		},
+		{
+			name: "pod assignment",
+			obj:  testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{PodName: "web-0"}),
+		},
+		{
+			name: "Lease in the namespace of the ReservedIP",
+			obj:  testReservedIP("a", &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"}),
+			want: []string{"default/leader"},
+		},
+		{
+			name: "Lease of a ClusterReservedIP",
+			obj:  clusterReservedIP,
+			want: []string{"kube-system/leader"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leaseIndexKey(tt.obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("leaseIndexKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeasePredicate(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *coordinationv1.Lease
+		new  *coordinationv1.Lease
+		want bool
+	}{
+		{
+			name: "renewal by the same holder",
+			old:  testLease("default", stringPtr("web-0")),
+			new:  testLease("default", stringPtr("web-0")),
+		},
+		{
+			name: "holder changed",
+			old:  testLease("default", stringPtr("web-0")),
+			new:  testLease("default", stringPtr("web-1")),
+			want: true,
+		},
+		{
+			name: "Lease released",
+			old:  testLease("default", stringPtr("web-0")),
+			new:  testLease("default", nil),
+			want: true,
+		},
+		{
+			name: "node heartbeat",
+			old:  testLease(corev1.NamespaceNodeLease, stringPtr("node-a")),
+			new:  testLease(corev1.NamespaceNodeLease, stringPtr("node-b")),
+		},
+	}
+
+	p := leasePredicate()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a renewal only changes the renew time
+			tt.new.Spec.RenewTime = &metav1.MicroTime{Time: testCreated.Time}
+			if got := p.Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}); got != tt.want {
+				t.Errorf("Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if p.Create(event.CreateEvent{Object: testLease(corev1.NamespaceNodeLease, nil)}) {
+		t.Error("Create() of a node Lease = true, want false")
+	}
+	if !p.Create(event.CreateEvent{Object: testLease("default", nil)}) {
+		t.Error("Create() of a Lease = false, want true")
+	}
+	if !p.Delete(event.DeleteEvent{Object: testLease("default", stringPtr("web-0"))}) {
+		t.Error("Delete() of a Lease = false, want true")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func (r *ReservedIPReconciler) isDryRun(reservedIP reservedIPObject) bool {
+	return r.DryRun || reservedIP.GetAnnotations()[ociv1alpha1.DryRunAnnotation] == "true"
+}
+
+// dryRunClient turns status updates and deletions into server-side dry runs,
+// so ReservedIPs in dry-run mode keep their status, while the reconciler still
+// sees the status it would have set.
+type dryRunClient struct {
+	client.Client
+}
+
+func (c dryRunClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (c dryRunClient) Status() client.StatusWriter {
+	return dryRunStatusWriter{c.Client.Status()}
+}
+
+type dryRunStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w dryRunStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return w.StatusWriter.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (w dryRunStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return w.StatusWriter.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+// describePlan describes the OCI calls of the plan and the state they would
+// move a ReservedIP in the given state to. It is empty if the plan neither
+// calls OCI nor changes the state.
+func describePlan(state string, p plan) string {
+	next := state
+	if p.State != "" {
+		next = p.State
+	}
+	var calls []string
+	for _, a := range p.Actions {
+		switch a {
+		case actionAddFinalizer, actionRemoveFinalizer:
+			continue
+		case actionAllocate, actionUnassign:
+			next = "allocated"
+		case actionAssign:
+			next = "assigned"
+		}
+		calls = append(calls, string(a))
+	}
+
+	switch {
+	case len(calls) > 0:
+		return fmt.Sprintf("Would call %s and move from state %q to %q", strings.Join(calls, ", "), state, next)
+	case next != state:
+		return fmt.Sprintf("Would move from state %q to %q", state, next)
+	}
+	return ""
+}
+
+// dryRun logs and records an event for the plan instead of executing it. Only
+// the finalizer is still added and removed, so ReservedIPs can be deleted in
+// dry-run mode; their public IP is then left in OCI.
+func (r *ReservedIPReconciler) dryRun(ctx context.Context, reservedIP reservedIPObject, p plan, log logr.Logger) error {
+	status := reservedIP.GetStatus()
+	if message := describePlan(status.State, p); message != "" {
+		log.Info("dry run: not calling OCI API", "state", status.State, "nextState", p.State, "actions", p.Actions)
+		r.Recorder.Event(reservedIP, "Normal", "DryRun", message)
+	}
+
+	for _, a := range p.Actions {
+		switch a {
+		case actionAddFinalizer:
+			if err := addFinalizer(ctx, r.Client, reservedIP); err != nil {
+				return err
+			}
+		case actionRemoveFinalizer:
+			if status.OCID != "" {
+				r.Recorder.Event(reservedIP, "Warning", "Abandoned", fmt.Sprintf("Deleted in dry-run mode without releasing public IP %s (%s) in OCI; delete it manually", status.OCID, status.PublicIPAddress))
+			}
+			if err := removeFinalizer(ctx, r.Client, reservedIP); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestDescribePlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		plan  plan
+		want  string
+	}{
+		{
+			name:  "nothing to do",
+			state: "assigned",
+		},
+		{
+			name: "only the finalizer",
+			plan: plan{Actions: []action{actionAddFinalizer}},
+		},
+		{
+			name: "allocate",
+			plan: plan{State: "allocating", Actions: []action{actionAllocate}},
+			want: `Would call Allocate and move from state "" to "allocated"`,
+		},
+		{
+			name:  "update tags and assign",
+			state: "allocated",
+			plan:  plan{State: "assigning", Actions: []action{actionUpdateTags, actionAssign}},
+			want:  `Would call UpdateTags, Assign and move from state "allocated" to "assigned"`,
+		},
+		{
+			name:  "update tags",
+			state: "assigned",
+			plan:  plan{Actions: []action{actionUpdateTags}},
+			want:  `Would call UpdateTags and move from state "assigned" to "assigned"`,
+		},
+		{
+			name:  "unassign",
+			state: "assigned",
+			plan:  plan{State: "unassigning", Actions: []action{actionUnassign}},
+			want:  `Would call Unassign and move from state "assigned" to "allocated"`,
+		},
+		{
+			name:  "release",
+			state: "assigned",
+			plan:  plan{State: "releasing", Actions: []action{actionRelease, actionRemoveFinalizer}},
+			want:  `Would call Release and move from state "assigned" to "releasing"`,
+		},
+		{
+			name:  "state change only",
+			state: "assigning",
+			plan:  plan{State: "allocated"},
+			want:  `Would move from state "assigning" to "allocated"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describePlan(tt.state, tt.plan); got != tt.want {
+				t.Errorf("describePlan() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRunClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reservedIP := &ociv1alpha1.ReservedIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"},
+		Status:     ociv1alpha1.ReservedIPStatus{State: "allocated"},
+	}
+	c := dryRunClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(reservedIP).Build()}
+	ctx := context.Background()
+
+	patch := mergeFrom(reservedIP)
+	reservedIP.Status.State = "assigning"
+	if err := c.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Status().Update(ctx, reservedIP); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, reservedIP); err != nil {
+		t.Fatal(err)
+	}
+
+	var got ociv1alpha1.ReservedIP
+	if err := c.Get(ctx, client.ObjectKeyFromObject(reservedIP), &got); err != nil {
+		t.Fatalf("ReservedIP was deleted: %v", err)
+	}
+	if got.Status.State != "allocated" {
+		t.Errorf("state = %q, want %q", got.Status.State, "allocated")
+	}
+}
@@ -0,0 +1,183 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// maxExpiryWarning is how long before a ReservedIP expires the Expiring
+// warning is given at most.
+const maxExpiryWarning = time.Hour
+
+// expiryWarning returns how long before the TTL expires to warn about it
+func expiryWarning(ttl time.Duration) time.Duration {
+	if warning := ttl / 4; warning < maxExpiryWarning {
+		return warning
+	}
+	return maxExpiryWarning
+}
+
+// expiryRefresh returns when to update the remaining lifetime in the status
+// again: the closer the expiration, the more often.
+func expiryRefresh(remaining time.Duration) time.Duration {
+	refresh := remaining / 10
+	if refresh < time.Minute {
+		refresh = time.Minute
+	}
+	if refresh > time.Hour {
+		refresh = time.Hour
+	}
+	return refresh
+}
+
+// expiryDeadline returns when a ReservedIP whose TTL expires at expiration
+// actually expires. If the Expiring warning was given late, e.g. because the
+// TTL was shortened or the operator was down, it expires no earlier than the
+// full warning period after the warning.
+func expiryDeadline(expiration time.Time, warning time.Duration, condition *metav1.Condition) time.Time {
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		return expiration
+	}
+	if deadline := condition.LastTransitionTime.Add(warning); deadline.After(expiration) {
+		return deadline
+	}
+	return expiration
+}
+
+// isExpired reports whether the TTL of the ReservedIP expired and it was kept
+// because of its reclaim policy Retain. Such ReservedIPs are unassigned.
+func isExpired(status *ociv1alpha1.ReservedIPStatus) bool {
+	condition := meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPExpiring)
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == "Expired"
+}
+
+// reconcileExpiry keeps the expiration of spec.ttl in the status, warns
+// before it and expires the ReservedIP. A ReservedIP only expires after the
+// warning was given. It returns when to check again and whether the
+// ReservedIP was deleted.
+func (r *ReservedIPReconciler) reconcileExpiry(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) (time.Duration, bool, error) {
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	if spec.TTL == nil {
+		if status.ExpirationTime == nil && meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPExpiring) == nil {
+			return 0, false, nil
+		}
+		patch := mergeFrom(reservedIP)
+		status.ExpirationTime = nil
+		status.RemainingLifetime = ""
+		meta.RemoveStatusCondition(&status.Conditions, ociv1alpha1.ReservedIPExpiring)
+		return 0, false, r.Status().Patch(ctx, reservedIP, patch, fieldOwner)
+	}
+
+	warning := expiryWarning(spec.TTL.Duration)
+	condition := meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPExpiring)
+	warned := condition != nil && condition.Status == metav1.ConditionTrue
+	expiration := metav1.NewTime(expiryDeadline(reservedIP.GetCreationTimestamp().Add(spec.TTL.Duration), warning, condition))
+	remaining := time.Until(expiration.Time)
+	if remaining <= 0 && warned {
+		deleted, err := r.expire(ctx, reservedIP, expiration, log)
+		return 0, deleted, err
+	}
+
+	patch := mergeFrom(reservedIP)
+	if remaining > warning {
+		meta.RemoveStatusCondition(&status.Conditions, ociv1alpha1.ReservedIPExpiring)
+	} else if !warned || condition.Reason == "Expired" {
+		// not warned yet, or the TTL was extended after it expired. The
+		// warning period starts now, so an earlier warning or expiration
+		// mustn't shorten it.
+		meta.RemoveStatusCondition(&status.Conditions, ociv1alpha1.ReservedIPExpiring)
+		expiration = metav1.NewTime(expiryDeadline(reservedIP.GetCreationTimestamp().Add(spec.TTL.Duration), warning, &metav1.Condition{
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+		}))
+		remaining = time.Until(expiration.Time)
+		message := fmt.Sprintf("TTL of %s expires at %s", spec.TTL.Duration, expiration.UTC().Format(time.RFC3339))
+		log.Info("ReservedIP expires soon", "expirationTime", expiration)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ociv1alpha1.ReservedIPExpiring,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Expiring",
+			Message: message,
+		})
+		r.Recorder.Event(reservedIP, "Warning", "Expiring", fmt.Sprintf("%s; the ReservedIP will be %s", message, expiryAction(spec)))
+	}
+	status.ExpirationTime = &expiration
+	status.RemainingLifetime = duration.HumanDuration(remaining)
+
+	requeue := expiryRefresh(remaining)
+	if remaining > warning && remaining-warning < requeue {
+		requeue = remaining - warning
+	}
+	if remaining < requeue {
+		requeue = remaining
+	}
+	return requeue, false, r.Status().Patch(ctx, reservedIP, patch, fieldOwner)
+}
+
+// expiryAction describes what happens to the ReservedIP when it expires
+func expiryAction(spec *ociv1alpha1.ReservedIPSpec) string {
+	if spec.ReclaimPolicy == ociv1alpha1.ReservedIPReclaimRetain {
+		return "unassigned (reclaim policy Retain)"
+	}
+	return "deleted and its public IP released"
+}
+
+// expire deletes the expired ReservedIP or, if its reclaim policy is Retain,
+// marks it as expired in the status, which makes the reconciler unassign it
+// and refuse to assign it again. It returns whether the ReservedIP was
+// deleted.
+func (r *ReservedIPReconciler) expire(ctx context.Context, reservedIP reservedIPObject, expiration metav1.Time, log logr.Logger) (bool, error) {
+	spec := reservedIP.GetSpec()
+	status := reservedIP.GetStatus()
+	message := fmt.Sprintf("TTL of %s expired at %s", spec.TTL.Duration, expiration.UTC().Format(time.RFC3339))
+
+	if spec.ReclaimPolicy != ociv1alpha1.ReservedIPReclaimRetain {
+		log.Info("ReservedIP expired; deleting", "expirationTime", expiration)
+		r.Recorder.Event(reservedIP, "Warning", "Expired", message+"; deleting")
+		return true, client.IgnoreNotFound(r.Delete(ctx, reservedIP))
+	}
+
+	if isExpired(status) {
+		return false, nil
+	}
+	log.Info("ReservedIP expired; unassigning it as its reclaim policy is Retain", "expirationTime", expiration)
+	patch := mergeFrom(reservedIP)
+	status.ExpirationTime = &expiration
+	status.RemainingLifetime = "0s"
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    ociv1alpha1.ReservedIPExpiring,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Expired",
+		Message: message + "; remove or extend spec.ttl to assign it again",
+	})
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return false, err
+	}
+	r.Recorder.Event(reservedIP, "Warning", "Expired", message+"; unassigning, the ReservedIP is kept because of its reclaim policy Retain")
+	return false, nil
+}
@@ -0,0 +1,235 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestExpiryWarning(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{ttl: time.Minute, want: 15 * time.Second},
+		{ttl: time.Hour, want: 15 * time.Minute},
+		{ttl: 4 * time.Hour, want: time.Hour},
+		{ttl: 72 * time.Hour, want: time.Hour},
+		{ttl: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ttl.String(), func(t *testing.T) {
+			if got := expiryWarning(tt.ttl); got != tt.want {
+				t.Errorf("expiryWarning(%s) = %s, want %s", tt.ttl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryRefresh(t *testing.T) {
+	tests := []struct {
+		remaining time.Duration
+		want      time.Duration
+	}{
+		{remaining: 30 * time.Second, want: time.Minute},
+		{remaining: 10 * time.Minute, want: time.Minute},
+		{remaining: time.Hour, want: 6 * time.Minute},
+		{remaining: 10 * time.Hour, want: time.Hour},
+		{remaining: 72 * time.Hour, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remaining.String(), func(t *testing.T) {
+			if got := expiryRefresh(tt.remaining); got != tt.want {
+				t.Errorf("expiryRefresh(%s) = %s, want %s", tt.remaining, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryDeadline(t *testing.T) {
+	expiration := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	warned := func(at time.Time, reason string) *metav1.Condition {
+		return &metav1.Condition{Type: ociv1alpha1.ReservedIPExpiring, Status: metav1.ConditionTrue, Reason: reason, LastTransitionTime: metav1.NewTime(at)}
+	}
+
+	tests := []struct {
+		name      string
+		condition *metav1.Condition
+		want      time.Time
+	}{
+		{
+			name: "not warned yet",
+			want: expiration,
+		},
+		{
+			name:      "warned in time",
+			condition: warned(expiration.Add(-time.Hour), "Expiring"),
+			want:      expiration,
+		},
+		{
+			name:      "warned late",
+			condition: warned(expiration.Add(-10*time.Minute), "Expiring"),
+			want:      expiration.Add(50 * time.Minute),
+		},
+		{
+			name:      "warned after the TTL expired",
+			condition: warned(expiration.Add(time.Hour), "Expiring"),
+			want:      expiration.Add(2 * time.Hour),
+		},
+		{
+			name:      "expired after a late warning",
+			condition: warned(expiration.Add(time.Hour), "Expired"),
+			want:      expiration.Add(2 * time.Hour),
+		},
+		{
+			name:      "warning withdrawn",
+			condition: &metav1.Condition{Type: ociv1alpha1.ReservedIPExpiring, Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(expiration.Add(time.Hour))},
+			want:      expiration,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiryDeadline(expiration, time.Hour, tt.condition); !got.Equal(tt.want) {
+				t.Errorf("expiryDeadline() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []metav1.Condition
+		want       bool
+	}{
+		{
+			name: "without TTL",
+		},
+		{
+			name:       "expiring",
+			conditions: []metav1.Condition{{Type: ociv1alpha1.ReservedIPExpiring, Status: metav1.ConditionTrue, Reason: "Expiring"}},
+		},
+		{
+			name:       "expired",
+			conditions: []metav1.Condition{{Type: ociv1alpha1.ReservedIPExpiring, Status: metav1.ConditionTrue, Reason: "Expired"}},
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isExpired(&ociv1alpha1.ReservedIPStatus{Conditions: tt.conditions}); got != tt.want {
+				t.Errorf("isExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileExpiry(t *testing.T) {
+	now := time.Now()
+	condition := func(ago time.Duration, reason string) []metav1.Condition {
+		return []metav1.Condition{{Type: ociv1alpha1.ReservedIPExpiring, Status: metav1.ConditionTrue, Reason: reason, LastTransitionTime: metav1.NewTime(now.Add(-ago))}}
+	}
+
+	tests := []struct {
+		name       string
+		age        time.Duration
+		ttl        time.Duration
+		conditions []metav1.Condition
+		// expected time left and age of the warning
+		wantRemaining time.Duration
+		wantWarnedAgo time.Duration
+	}{
+		{
+			name:          "gives the full warning period when warning late",
+			age:           50 * time.Minute,
+			ttl:           time.Hour,
+			wantRemaining: 15 * time.Minute,
+		},
+		{
+			name:          "keeps an earlier warning",
+			age:           50 * time.Minute,
+			ttl:           time.Hour,
+			conditions:    condition(5*time.Minute, "Expiring"),
+			wantRemaining: 10 * time.Minute,
+			wantWarnedAgo: 5 * time.Minute,
+		},
+		{
+			name:          "warns again after the TTL was extended past its expiration",
+			age:           2 * time.Hour,
+			ttl:           2*time.Hour + 10*time.Minute,
+			conditions:    condition(75*time.Minute, "Expired"),
+			wantRemaining: 32*time.Minute + 30*time.Second,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reservedIP := testReservedIP("a", nil)
+			reservedIP.CreationTimestamp = metav1.NewTime(now.Add(-tt.age))
+			reservedIP.Spec.TTL = &metav1.Duration{Duration: tt.ttl}
+			reservedIP.Spec.ReclaimPolicy = ociv1alpha1.ReservedIPReclaimRetain
+			reservedIP.Status.Conditions = tt.conditions
+			r := &ReservedIPReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(reservedIP.DeepCopy()).Build(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			if _, deleted, err := r.reconcileExpiry(context.Background(), reservedIP, logr.Discard()); err != nil || deleted {
+				t.Fatalf("reconcileExpiry() = %v, %v", deleted, err)
+			}
+
+			// the time stored in the condition has a precision of a second
+			near := func(got time.Time, want time.Time) bool {
+				return got.Sub(want) < 2*time.Second && want.Sub(got) < 2*time.Second
+			}
+			if got := reservedIP.Status.ExpirationTime; got == nil || !near(got.Time, now.Add(tt.wantRemaining)) {
+				t.Errorf("expirationTime = %v, want in %s", got, tt.wantRemaining)
+			}
+			got := meta.FindStatusCondition(reservedIP.Status.Conditions, ociv1alpha1.ReservedIPExpiring)
+			if got == nil || got.Status != metav1.ConditionTrue || got.Reason != "Expiring" {
+				t.Fatalf("Expiring condition = %+v", got)
+			}
+			if !near(got.LastTransitionTime.Time, now.Add(-tt.wantWarnedAgo)) {
+				t.Errorf("warned at %s, want %s ago", got.LastTransitionTime, tt.wantWarnedAgo)
+			}
+			// the next reconcile must agree on the expiration
+			expiration := reservedIP.CreationTimestamp.Add(tt.ttl)
+			if deadline := expiryDeadline(expiration, expiryWarning(tt.ttl), got); !near(deadline, reservedIP.Status.ExpirationTime.Time) {
+				t.Errorf("expiryDeadline() = %s, want %s", deadline, reservedIP.Status.ExpirationTime)
+			}
+		})
+	}
+}
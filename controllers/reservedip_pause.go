@@ -0,0 +1,155 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func isPaused(reservedIP reservedIPObject) bool {
+	return reservedIP.GetSpec().Paused || reservedIP.GetAnnotations()[ociv1alpha1.PausedAnnotation] == "true"
+}
+
+// pause sets the Paused condition. Nothing else is done for paused
+// ReservedIPs until they are resumed or deleted; deleted ones are released as
+// usual so they don't keep their finalizer forever.
+func (r *ReservedIPReconciler) pause(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
+	status := reservedIP.GetStatus()
+	if meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPPaused) {
+		return nil
+	}
+
+	log.Info("paused")
+	patch := mergeFrom(reservedIP)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    ociv1alpha1.ReservedIPPaused,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Paused",
+		Message: "Reconciliation is paused; the public IP isn't changed in OCI",
+	})
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return err
+	}
+	r.Recorder.Event(reservedIP, "Normal", "Paused", "Reconciliation paused")
+	return nil
+}
+
+// resume checks whether the public IP was changed in OCI while the ReservedIP
+// was paused, moves the state machine to the state that restores the spec,
+// and clears the Paused condition.
+func (r *ReservedIPReconciler) resume(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) error {
+	patch := mergeFrom(reservedIP)
+	drift, err := r.checkDrift(ctx, reservedIP)
+	if err != nil {
+		return err
+	}
+
+	status := reservedIP.GetStatus()
+	message := "Reconciliation resumed; no changes found in OCI"
+	if len(drift) > 0 {
+		message = fmt.Sprintf("Reconciliation resumed; found changes in OCI: %s", strings.Join(drift, "; "))
+		r.Recorder.Event(reservedIP, "Warning", "DriftDetected", message)
+	}
+	log.Info("resumed", "drift", drift, "state", status.State)
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    ociv1alpha1.ReservedIPPaused,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Resumed",
+		Message: message,
+	})
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return err
+	}
+	r.Recorder.Event(reservedIP, "Normal", "Resumed", "Reconciliation resumed")
+	return nil
+}
+
+// checkDrift compares the public IP in OCI with the status of the ReservedIP.
+// It returns a description of each difference, and changes the state so the
+// state machine brings the public IP back in line with the spec.
+func (r *ReservedIPReconciler) checkDrift(ctx context.Context, reservedIP reservedIPObject) ([]string, error) {
+	status := reservedIP.GetStatus()
+	if status.OCID == "" {
+		// not allocated yet, nothing to compare
+		return nil, nil
+	}
+
+	resp, err := r.VNC.GetPublicIp(ctx, ocicore.GetPublicIpRequest{
+		PublicIpId: &status.OCID,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "NotAuthorizedOrNotFound") {
+			return publicIPDrift(reservedIP.GetSpec(), status, nil), nil
+		}
+		return nil, err
+	}
+	return publicIPDrift(reservedIP.GetSpec(), status, &resp.PublicIp), nil
+}
+
+// publicIPDrift compares the public IP in OCI, nil if it wasn't found, with the
+// status. It returns a description of each difference and updates the status
+// accordingly.
+func publicIPDrift(spec *ociv1alpha1.ReservedIPSpec, status *ociv1alpha1.ReservedIPStatus, addr *ocicore.PublicIp) []string {
+	if addr == nil {
+		// deleted in OCI; allocate a new one
+		d := []string{fmt.Sprintf("public IP %s not found", status.OCID)}
+		status.State = ""
+		status.OCID = ""
+		status.PublicIPAddress = ""
+		status.Assignment = nil
+		status.PrivateIPAddressID = ""
+		return d
+	}
+
+	var d []string
+	if addr.IpAddress != nil && *addr.IpAddress != status.PublicIPAddress {
+		d = append(d, fmt.Sprintf("address changed from %s to %s", status.PublicIPAddress, *addr.IpAddress))
+		status.PublicIPAddress = *addr.IpAddress
+	}
+
+	assignedTo := ""
+	if addr.AssignedEntityId != nil {
+		assignedTo = *addr.AssignedEntityId
+	}
+	switch status.State {
+	case "assigned":
+		if assignedTo != status.PrivateIPAddressID {
+			d = append(d, fmt.Sprintf("assigned to %q instead of %q", assignedTo, status.PrivateIPAddressID))
+			status.State = "reassigning"
+		}
+	case "allocated":
+		if assignedTo != "" {
+			d = append(d, fmt.Sprintf("assigned to %q instead of unassigned", assignedTo))
+			status.State = "unassigning"
+		}
+	}
+
+	if spec.Tags != nil && !tagsMatch(*spec.Tags, addr.FreeformTags) {
+		d = append(d, "tags changed")
+	}
+	return d
+}
@@ -0,0 +1,135 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v31/common"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestPublicIPDrift(t *testing.T) {
+	allocated := ociv1alpha1.ReservedIPStatus{State: "allocated", OCID: testOCID, PublicIPAddress: "1.2.3.4"}
+	assigned := ociv1alpha1.ReservedIPStatus{
+		State:              "assigned",
+		OCID:               testOCID,
+		PublicIPAddress:    "1.2.3.4",
+		Assignment:         &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"},
+		PrivateIPAddressID: testPrivateIPID,
+	}
+	inState := func(state string, status ociv1alpha1.ReservedIPStatus) ociv1alpha1.ReservedIPStatus {
+		status.State = state
+		return status
+	}
+	tags := map[string]string{"team": "a"}
+	addr := func(ip, assignedTo string, tags map[string]string) *ocicore.PublicIp {
+		a := &ocicore.PublicIp{IpAddress: common.String(ip), FreeformTags: tags}
+		if assignedTo != "" {
+			a.AssignedEntityId = common.String(assignedTo)
+		}
+		return a
+	}
+
+	tests := []struct {
+		name       string
+		spec       ociv1alpha1.ReservedIPSpec
+		status     ociv1alpha1.ReservedIPStatus
+		addr       *ocicore.PublicIp
+		want       []string
+		wantStatus ociv1alpha1.ReservedIPStatus
+	}{
+		{
+			name:       "unchanged",
+			status:     assigned,
+			addr:       addr("1.2.3.4", testPrivateIPID, nil),
+			wantStatus: assigned,
+		},
+		{
+			name:       "assigned elsewhere",
+			status:     assigned,
+			addr:       addr("1.2.3.4", "ocid1.privateip.oc1..other", nil),
+			want:       []string{`assigned to "ocid1.privateip.oc1..other" instead of "` + testPrivateIPID + `"`},
+			wantStatus: inState("reassigning", assigned),
+		},
+		{
+			name:       "unassigned",
+			status:     assigned,
+			addr:       addr("1.2.3.4", "", nil),
+			want:       []string{`assigned to "" instead of "` + testPrivateIPID + `"`},
+			wantStatus: inState("reassigning", assigned),
+		},
+		{
+			name:       "assigned while it shouldn't be",
+			status:     allocated,
+			addr:       addr("1.2.3.4", testPrivateIPID, nil),
+			want:       []string{`assigned to "` + testPrivateIPID + `" instead of unassigned`},
+			wantStatus: inState("unassigning", allocated),
+		},
+		{
+			name:       "address changed",
+			status:     allocated,
+			addr:       addr("5.6.7.8", "", nil),
+			want:       []string{"address changed from 1.2.3.4 to 5.6.7.8"},
+			wantStatus: ociv1alpha1.ReservedIPStatus{State: "allocated", OCID: testOCID, PublicIPAddress: "5.6.7.8"},
+		},
+		{
+			name:       "tags changed",
+			spec:       ociv1alpha1.ReservedIPSpec{Tags: &tags},
+			status:     allocated,
+			addr:       addr("1.2.3.4", "", map[string]string{"team": "b"}),
+			want:       []string{"tags changed"},
+			wantStatus: allocated,
+		},
+		{
+			name:       "cluster tag added by the operator",
+			spec:       ociv1alpha1.ReservedIPSpec{Tags: &tags},
+			status:     allocated,
+			addr:       addr("1.2.3.4", "", map[string]string{"team": "a", clusterTag: "cluster-a"}),
+			wantStatus: allocated,
+		},
+		{
+			name:       "tags changed besides the cluster tag",
+			spec:       ociv1alpha1.ReservedIPSpec{Tags: &tags},
+			status:     allocated,
+			addr:       addr("1.2.3.4", "", map[string]string{"team": "b", clusterTag: "cluster-a"}),
+			want:       []string{"tags changed"},
+			wantStatus: allocated,
+		},
+		{
+			name:       "not found",
+			status:     assigned,
+			want:       []string{"public IP " + testOCID + " not found"},
+			wantStatus: ociv1alpha1.ReservedIPStatus{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := *tt.status.DeepCopy()
+			got := publicIPDrift(&tt.spec, &status, tt.addr)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("publicIPDrift() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(status, tt.wantStatus) {
+				t.Errorf("status = %+v, want %+v", status, tt.wantStatus)
+			}
+		})
+	}
+}
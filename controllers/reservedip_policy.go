@@ -0,0 +1,237 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedippolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// assignmentType returns the kind of target of the assignment
+func assignmentType(assignment *ociv1alpha1.ReservedIPAssignment) ociv1alpha1.ReservedIPAssignmentType {
+	switch {
+	case assignment.LeaseName != "":
+		return ociv1alpha1.AssignmentTypeLease
+	case assignment.PodName != "":
+		return ociv1alpha1.AssignmentTypePod
+	case assignment.PrivateIPAddress != "":
+		return ociv1alpha1.AssignmentTypePrivateIPAddress
+	}
+	return ""
+}
+
+// policyViolations returns how the spec violates the policy. compartmentID is
+// the compartment the ReservedIP is created in, empty for the default one.
+func policyViolations(policy *ociv1alpha1.ReservedIPPolicySpec, spec *ociv1alpha1.ReservedIPSpec, compartmentID string) []string {
+	var violations []string
+	if assignment := spec.Assignment; assignment != nil {
+		if t := assignmentType(assignment); t != "" && len(policy.AllowedAssignmentTypes) > 0 && !containsAssignmentType(policy.AllowedAssignmentTypes, t) {
+			violations = append(violations, fmt.Sprintf("assignment type %s is not allowed", t))
+		}
+		if assignment.PrivateIPAddress != "" && len(policy.AllowedPrivateIPCIDRs) > 0 && !inCIDRs(policy.AllowedPrivateIPCIDRs, assignment.PrivateIPAddress) {
+			violations = append(violations, fmt.Sprintf("private IP %s is not in the allowed CIDRs %s", assignment.PrivateIPAddress, strings.Join(policy.AllowedPrivateIPCIDRs, ", ")))
+		}
+		if assignment.AllowTakeover && policy.AllowTakeover != nil && !*policy.AllowTakeover {
+			violations = append(violations, "allowTakeover is not allowed")
+		}
+	}
+	if spec.PublicIPPoolID != "" && len(policy.AllowedPublicIPPoolIDs) > 0 && !containsString(policy.AllowedPublicIPPoolIDs, spec.PublicIPPoolID) {
+		violations = append(violations, fmt.Sprintf("public IP pool %s is not allowed", spec.PublicIPPoolID))
+	}
+	if compartmentID != "" && len(policy.AllowedCompartmentIDs) > 0 && !containsString(policy.AllowedCompartmentIDs, compartmentID) {
+		violations = append(violations, fmt.Sprintf("compartment %s is not allowed", compartmentID))
+	}
+	if spec.AccountName != "" && len(policy.AllowedAccountNames) > 0 && !containsString(policy.AllowedAccountNames, spec.AccountName) {
+		violations = append(violations, fmt.Sprintf("OCIAccount %s is not allowed", spec.AccountName))
+	}
+	return violations
+}
+
+func containsAssignmentType(types []ociv1alpha1.ReservedIPAssignmentType, t ociv1alpha1.ReservedIPAssignmentType) bool {
+	for _, item := range types {
+		if item == t {
+			return true
+		}
+	}
+	return false
+}
+
+// inCIDRs reports whether ip is in any of the CIDRs. Invalid CIDRs don't
+// match anything.
+func inCIDRs(cidrs []string, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveCompartmentID returns the compartment the ReservedIP is created
+// in: spec.compartmentID, or else the compartment of its OCIAccount. It is
+// empty for the operator's default compartment, or if the OCIAccount doesn't
+// exist, which is reported when reconciling the ReservedIP instead.
+func effectiveCompartmentID(ctx context.Context, c client.Reader, spec *ociv1alpha1.ReservedIPSpec) (string, error) {
+	if spec.CompartmentID != "" || spec.AccountName == "" {
+		return spec.CompartmentID, nil
+	}
+	var account ociv1alpha1.OCIAccount
+	if err := c.Get(ctx, client.ObjectKey{Name: spec.AccountName}, &account); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return account.Spec.CompartmentID, nil
+}
+
+// checkPolicies returns how the spec of a ReservedIP in the given namespace
+// violates the ReservedIPPolicies selecting the namespace.
+func checkPolicies(ctx context.Context, c client.Reader, namespace string, spec *ociv1alpha1.ReservedIPSpec) ([]string, error) {
+	var policies ociv1alpha1.ReservedIPPolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	var ns corev1.Namespace
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, &ns); err != nil {
+		return nil, err
+	}
+
+	compartmentID, err := effectiveCompartmentID(ctx, c, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, policy := range policies.Items {
+		if policy.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespaceSelector of ReservedIPPolicy %s: %w", policy.Name, err)
+			}
+			if !selector.Matches(labels.Set(ns.Labels)) {
+				continue
+			}
+		}
+		for _, violation := range policyViolations(&policy.Spec, spec, compartmentID) {
+			violations = append(violations, fmt.Sprintf("ReservedIPPolicy %s: %s", policy.Name, violation))
+		}
+	}
+	return violations, nil
+}
+
+// enforcePolicies sets the PolicyViolation condition of a ReservedIP and
+// returns true if it violates a ReservedIPPolicy, e.g. because it was created
+// before the policy, its namespace was relabelled or the admission webhook was
+// down. Such ReservedIPs are neither allocated nor assigned, and unassigned if
+// they are, until they are fixed.
+func (r *ReservedIPReconciler) enforcePolicies(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) (bool, error) {
+	if reservedIP.GetNamespace() == "" {
+		return false, nil
+	}
+	violations, err := checkPolicies(ctx, r.Client, reservedIP.GetNamespace(), reservedIP.GetSpec())
+	if err != nil {
+		return false, err
+	}
+
+	status := reservedIP.GetStatus()
+	if len(violations) == 0 {
+		if !meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPPolicyViolation) {
+			return false, nil
+		}
+		patch := mergeFrom(reservedIP)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ociv1alpha1.ReservedIPPolicyViolation,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Allowed",
+			Message: "The ReservedIP satisfies all ReservedIPPolicies",
+		})
+		return false, r.Status().Patch(ctx, reservedIP, patch, fieldOwner)
+	}
+
+	message := strings.Join(violations, "; ")
+	condition := meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPPolicyViolation)
+	if condition != nil && condition.Status == metav1.ConditionTrue && condition.Message == message {
+		return true, nil
+	}
+	log.Info("ReservedIP violates ReservedIPPolicies; not allocating or assigning it", "violations", violations)
+	patch := mergeFrom(reservedIP)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    ociv1alpha1.ReservedIPPolicyViolation,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PolicyViolation",
+		Message: message,
+	})
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return true, err
+	}
+	r.Recorder.Event(reservedIP, "Warning", "PolicyViolation", message)
+	return true, nil
+}
+
+// enqueueForNamespace enqueues the ReservedIPs of a namespace whose labels
+// changed, as ReservedIPPolicies and OCIAccounts may select or deselect it.
+func enqueueForNamespace(c client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		var reservedIPs ociv1alpha1.ReservedIPList
+		if err := c.List(context.Background(), &reservedIPs, client.InNamespace(obj.GetName())); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, reservedIP := range reservedIPs.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: reservedIP.Namespace, Name: reservedIP.Name}})
+		}
+		return requests
+	})
+}
+
+// enqueueForPolicy enqueues all ReservedIPs when a ReservedIPPolicy changes,
+// as its namespace selector may have selected or deselected any of them.
+func enqueueForPolicy(c client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		var reservedIPs ociv1alpha1.ReservedIPList
+		if err := c.List(context.Background(), &reservedIPs); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, reservedIP := range reservedIPs.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: reservedIP.Namespace, Name: reservedIP.Name}})
+		}
+		return requests
+	})
+}
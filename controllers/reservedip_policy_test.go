@@ -0,0 +1,173 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestPolicyViolations(t *testing.T) {
+	allowed, denied := true, false
+	policy := ociv1alpha1.ReservedIPPolicySpec{
+		AllowedAssignmentTypes: []ociv1alpha1.ReservedIPAssignmentType{ociv1alpha1.AssignmentTypePod, ociv1alpha1.AssignmentTypePrivateIPAddress},
+		AllowedPrivateIPCIDRs:  []string{"10.0.16.0/20"},
+		AllowedPublicIPPoolIDs: []string{"ocid1.publicippool.oc1..a"},
+		AllowedCompartmentIDs:  []string{"ocid1.compartment.oc1..a"},
+		AllowedAccountNames:    []string{"team-a"},
+		AllowTakeover:          &denied,
+	}
+
+	tests := []struct {
+		name          string
+		policy        ociv1alpha1.ReservedIPPolicySpec
+		spec          ociv1alpha1.ReservedIPSpec
+		compartmentID string
+		want          []string
+	}{
+		{
+			name:   "empty spec",
+			policy: policy,
+		},
+		{
+			name:   "everything allowed",
+			policy: policy,
+			spec: ociv1alpha1.ReservedIPSpec{
+				Assignment:     &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.16.5"},
+				PublicIPPoolID: "ocid1.publicippool.oc1..a",
+				CompartmentID:  "ocid1.compartment.oc1..a",
+				AccountName:    "team-a",
+			},
+			compartmentID: "ocid1.compartment.oc1..a",
+		},
+		{
+			name:   "empty policy doesn't restrict",
+			policy: ociv1alpha1.ReservedIPPolicySpec{},
+			spec: ociv1alpha1.ReservedIPSpec{
+				Assignment:     &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader", AllowTakeover: true},
+				PublicIPPoolID: "ocid1.publicippool.oc1..b",
+				AccountName:    "team-b",
+			},
+			compartmentID: "ocid1.compartment.oc1..b",
+		},
+		{
+			name:   "assignment type",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"}},
+			want:   []string{"assignment type Lease is not allowed"},
+		},
+		{
+			name:   "private IP outside the CIDRs",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.32.5"}},
+			want:   []string{"private IP 10.0.32.5 is not in the allowed CIDRs 10.0.16.0/20"},
+		},
+		{
+			name:   "pod IPs aren't restricted by CIDRs",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}},
+		},
+		{
+			name:   "public IP pool",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{PublicIPPoolID: "ocid1.publicippool.oc1..b"},
+			want:   []string{"public IP pool ocid1.publicippool.oc1..b is not allowed"},
+		},
+		{
+			name:          "compartment of the spec",
+			policy:        policy,
+			spec:          ociv1alpha1.ReservedIPSpec{CompartmentID: "ocid1.compartment.oc1..b"},
+			compartmentID: "ocid1.compartment.oc1..b",
+			want:          []string{"compartment ocid1.compartment.oc1..b is not allowed"},
+		},
+		{
+			name:          "compartment of the account",
+			policy:        ociv1alpha1.ReservedIPPolicySpec{AllowedCompartmentIDs: policy.AllowedCompartmentIDs},
+			spec:          ociv1alpha1.ReservedIPSpec{AccountName: "team-a"},
+			compartmentID: "ocid1.compartment.oc1..b",
+			want:          []string{"compartment ocid1.compartment.oc1..b is not allowed"},
+		},
+		{
+			name:   "default compartment is always allowed",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{},
+		},
+		{
+			name:   "account",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{AccountName: "team-b"},
+			want:   []string{"OCIAccount team-b is not allowed"},
+		},
+		{
+			name:   "takeover denied",
+			policy: policy,
+			spec:   ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", AllowTakeover: true}},
+			want:   []string{"allowTakeover is not allowed"},
+		},
+		{
+			name:   "takeover allowed",
+			policy: ociv1alpha1.ReservedIPPolicySpec{AllowTakeover: &allowed},
+			spec:   ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", AllowTakeover: true}},
+		},
+		{
+			name:   "several violations",
+			policy: policy,
+			spec: ociv1alpha1.ReservedIPSpec{
+				Assignment:     &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"},
+				PublicIPPoolID: "ocid1.publicippool.oc1..b",
+			},
+			want: []string{"assignment type Lease is not allowed", "public IP pool ocid1.publicippool.oc1..b is not allowed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policyViolations(&tt.policy, &tt.spec, tt.compartmentID); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policyViolations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInCIDRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		ip    string
+		want  bool
+	}{
+		{name: "inside", cidrs: []string{"10.0.16.0/20"}, ip: "10.0.31.255", want: true},
+		{name: "outside", cidrs: []string{"10.0.16.0/20"}, ip: "10.0.32.0", want: false},
+		{name: "second CIDR", cidrs: []string{"10.0.16.0/20", "192.168.0.0/16"}, ip: "192.168.1.1", want: true},
+		{name: "single address", cidrs: []string{"10.0.0.1/32"}, ip: "10.0.0.1", want: true},
+		{name: "invalid CIDR doesn't match", cidrs: []string{"10.0.16.0", "10.0.16.0/20"}, ip: "10.0.16.1", want: true},
+		{name: "only invalid CIDRs", cidrs: []string{"not-a-cidr"}, ip: "10.0.16.1", want: false},
+		{name: "invalid IP", cidrs: []string{"0.0.0.0/0"}, ip: "not-an-ip", want: false},
+		{name: "IPv6", cidrs: []string{"fd00::/64"}, ip: "fd00::1", want: true},
+		{name: "IPv4 not in IPv6 CIDR", cidrs: []string{"fd00::/64"}, ip: "10.0.0.1", want: false},
+		{name: "no CIDRs", ip: "10.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inCIDRs(tt.cidrs, tt.ip); got != tt.want {
+				t.Errorf("inCIDRs(%v, %s) = %v, want %v", tt.cidrs, tt.ip, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// observedState is what the reconciler observed about a ReservedIP outside of
+// its spec and status.
+type observedState struct {
+	// Deleting is set if the ReservedIP has a deletion timestamp.
+	Deleting bool
+	// HasFinalizer is set if the ReservedIP has the operator's finalizer.
+	HasFinalizer bool
+	// PublicIP is the public IP in OCI. It is only fetched once the
+	// ReservedIP is allocated.
+	PublicIP *ocicore.PublicIp
+	// LeaseHolder is the pod holding the Lease in spec.assignment.leaseName.
+	// It is only fetched for assigned ReservedIPs following a Lease.
+	LeaseHolder string
+	// MissingClusterTag is set if the public IP lacks the tag identifying the
+	// cluster, which the operator adds when updating the tags.
+	MissingClusterTag bool
+	// PolicyViolation is set if the ReservedIP violates a ReservedIPPolicy.
+	// It must then neither be allocated nor assigned.
+	PolicyViolation bool
+	// QuotaExceeded is set if allocating the ReservedIP, or assigning it if
+	// it is allocated, would exceed a ReservedIPQuota.
+	QuotaExceeded bool
+	// Expired is set if the TTL of the ReservedIP expired and it was kept
+	// because of its reclaim policy Retain. It must then not be assigned.
+	Expired bool
+}
+
+// action is a step the reconciler executes for a plan
+type action string
+
+const (
+	actionAddFinalizer    action = "AddFinalizer"
+	actionAllocate        action = "Allocate"
+	actionUpdateTags      action = "UpdateTags"
+	actionAssign          action = "Assign"
+	actionUnassign        action = "Unassign"
+	actionRelease         action = "Release"
+	actionRemoveFinalizer action = "RemoveFinalizer"
+)
+
+// plan is the outcome of transition. The reconciler first moves the ReservedIP
+// to State, if set, and then executes the actions in order. Allocate, Assign
+// and Unassign move the ReservedIP to allocated, assigned and allocated
+// respectively when they succeed.
+type plan struct {
+	State   string
+	Actions []action
+
+	// Reason and message of an event to record for the state change
+	EventReason  string
+	EventMessage string
+}
+
+// hasAssignmentTarget returns whether the spec says what to assign to
+func hasAssignmentTarget(spec ociv1alpha1.ReservedIPSpec) bool {
+	a := spec.Assignment
+	return a != nil && (a.PodName != "" || a.PrivateIPAddress != "" || a.LeaseName != "")
+}
+
+// tagsMatch reports whether the public IP has the tags of the spec, ignoring
+// the cluster tag added by the operator
+func tagsMatch(spec, existing map[string]string) bool {
+	if _, ok := existing[clusterTag]; ok {
+		withoutClusterTag := make(map[string]string, len(existing))
+		for key, value := range existing {
+			if key != clusterTag {
+				withoutClusterTag[key] = value
+			}
+		}
+		existing = withoutClusterTag
+	}
+	if len(spec) == 0 && len(existing) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(spec, existing)
+}
+
+// transition decides on the next step of the ReservedIP state machine, see
+// the state transfer diagram of ReservedIPStatus.State. It doesn't have side
+// effects.
+func transition(spec ociv1alpha1.ReservedIPSpec, status ociv1alpha1.ReservedIPStatus, observed observedState) plan {
+	if observed.Deleting {
+		if !observed.HasFinalizer {
+			return plan{}
+		}
+		if status.OCID == "" {
+			// nothing allocated in OCI
+			return plan{Actions: []action{actionRemoveFinalizer}}
+		}
+		p := plan{Actions: []action{actionRelease, actionRemoveFinalizer}}
+		if status.State != "releasing" {
+			p.State = "releasing"
+		}
+		return p
+	}
+
+	if !observed.HasFinalizer {
+		return plan{Actions: []action{actionAddFinalizer}}
+	}
+
+	switch status.State {
+	case "":
+		if observed.PolicyViolation || observed.QuotaExceeded {
+			return plan{}
+		}
+		return plan{State: "allocating", Actions: []action{actionAllocate}}
+	case "allocating":
+		if observed.PolicyViolation {
+			return plan{}
+		}
+		return plan{Actions: []action{actionAllocate}}
+	}
+
+	if observed.PublicIP == nil {
+		// the public IP couldn't be observed; nothing to decide on
+		return plan{}
+	}
+
+	var p plan
+	if observed.MissingClusterTag || (spec.Tags != nil && !tagsMatch(*spec.Tags, observed.PublicIP.FreeformTags)) {
+		p.Actions = append(p.Actions, actionUpdateTags)
+	}
+
+	if observed.PolicyViolation || observed.Expired {
+		switch status.State {
+		case "assigning", "assigned", "reassigning", "unassigning":
+			// the public IP may be assigned in OCI
+			if status.State != "unassigning" {
+				p.State = "unassigning"
+			}
+			p.Actions = append(p.Actions, actionUnassign)
+		}
+		return p
+	}
+
+	switch status.State {
+	case "allocated":
+		if hasAssignmentTarget(spec) && !observed.QuotaExceeded {
+			p.State = "assigning"
+			p.Actions = append(p.Actions, actionAssign)
+			p.EventReason, p.EventMessage = "Assigning", "Reserved IP assigned"
+		}
+	case "assigning":
+		if hasAssignmentTarget(spec) {
+			p.Actions = append(p.Actions, actionAssign)
+		} else {
+			// assignment was removed before the public IP was actually assigned
+			p.State = "allocated"
+		}
+	case "assigned":
+		assignedTo := observed.PublicIP.AssignedEntityId
+		switch {
+		case !hasAssignmentTarget(spec):
+			// assignment was removed
+			p.State = "unassigning"
+			p.Actions = append(p.Actions, actionUnassign)
+		case status.Assignment == nil || !status.Assignment.MatchesSpec(*spec.Assignment):
+			// assignment was changed in the spec
+			p.State = "reassigning"
+			p.Actions = append(p.Actions, actionAssign)
+		case assignedTo == nil || *assignedTo != status.PrivateIPAddressID:
+			// assignment was changed in OCI
+			p.State = "reassigning"
+			p.Actions = append(p.Actions, actionAssign)
+		case spec.Assignment.LeaseName != "" && observed.LeaseHolder != status.Assignment.PodName:
+			// leadership changed
+			p.State = "reassigning"
+			p.Actions = append(p.Actions, actionAssign)
+			p.EventReason = "LeaderChanged"
+			p.EventMessage = fmt.Sprintf("Lease %s is now held by pod %s", spec.Assignment.LeaseName, observed.LeaseHolder)
+		}
+	case "reassigning":
+		if hasAssignmentTarget(spec) {
+			p.Actions = append(p.Actions, actionAssign)
+		} else {
+			// assignment was removed while reassigning
+			p.State = "unassigning"
+			p.Actions = append(p.Actions, actionUnassign)
+		}
+	case "unassigning":
+		p.Actions = append(p.Actions, actionUnassign)
+	}
+	return p
+}
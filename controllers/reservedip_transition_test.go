@@ -0,0 +1,408 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+const (
+	testOCID        = "ocid1.publicip.oc1..test"
+	testPrivateIPID = "ocid1.privateip.oc1..test"
+)
+
+func publicIP(assignedTo string, tags map[string]string) *ocicore.PublicIp {
+	ip := &ocicore.PublicIp{
+		Id:           ocicommon.String(testOCID),
+		FreeformTags: tags,
+	}
+	if assignedTo != "" {
+		ip.AssignedEntityId = ocicommon.String(assignedTo)
+	}
+	return ip
+}
+
+func TestTransition(t *testing.T) {
+	podAssignment := &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}
+	leaseAssignment := &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"}
+	tags := map[string]string{"team": "net"}
+	assigned := ociv1alpha1.ReservedIPStatus{
+		State:              "assigned",
+		OCID:               testOCID,
+		Assignment:         &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", PrivateIPAddress: "10.0.0.1"},
+		PrivateIPAddressID: testPrivateIPID,
+	}
+	assignedToLeader := ociv1alpha1.ReservedIPStatus{
+		State:              "assigned",
+		OCID:               testOCID,
+		Assignment:         &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader", PodName: "pod-a", PrivateIPAddress: "10.0.0.1"},
+		PrivateIPAddressID: testPrivateIPID,
+	}
+	withState := func(state string) ociv1alpha1.ReservedIPStatus {
+		return ociv1alpha1.ReservedIPStatus{State: state, OCID: testOCID}
+	}
+
+	tests := []struct {
+		name     string
+		spec     ociv1alpha1.ReservedIPSpec
+		status   ociv1alpha1.ReservedIPStatus
+		observed observedState
+		want     plan
+	}{
+		{
+			name:     "new ReservedIP gets finalizer",
+			observed: observedState{},
+			want:     plan{Actions: []action{actionAddFinalizer}},
+		},
+		{
+			name:     "start allocating",
+			observed: observedState{HasFinalizer: true},
+			want:     plan{State: "allocating", Actions: []action{actionAllocate}},
+		},
+		{
+			name:     "retry allocating",
+			status:   ociv1alpha1.ReservedIPStatus{State: "allocating"},
+			observed: observedState{HasFinalizer: true},
+			want:     plan{Actions: []action{actionAllocate}},
+		},
+		{
+			name:     "allocated without assignment",
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{},
+		},
+		{
+			name:     "allocated with empty assignment",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{}},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{},
+		},
+		{
+			name:     "allocated public IP not observed",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true},
+			want:     plan{},
+		},
+		{
+			name:     "allocated with assignment starts assigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{State: "assigning", Actions: []action{actionAssign}, EventReason: "Assigning", EventMessage: "Reserved IP assigned"},
+		},
+		{
+			name:     "allocated with changed tags",
+			spec:     ociv1alpha1.ReservedIPSpec{Tags: &tags},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", map[string]string{"team": "old"})},
+			want:     plan{Actions: []action{actionUpdateTags}},
+		},
+		{
+			name:     "allocated with tags and the cluster tag",
+			spec:     ociv1alpha1.ReservedIPSpec{Tags: &tags},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", map[string]string{"team": "net", clusterTag: "cluster-a"})},
+			want:     plan{},
+		},
+		{
+			name:     "not allocated while violating a policy",
+			observed: observedState{HasFinalizer: true, PolicyViolation: true},
+			want:     plan{},
+		},
+		{
+			name:     "not assigned while violating a policy",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), PolicyViolation: true},
+			want:     plan{},
+		},
+		{
+			name:     "assigned and violating a policy is unassigned",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil), PolicyViolation: true},
+			want:     plan{State: "unassigning", Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "assigning and violating a policy is unassigned",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("assigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), PolicyViolation: true},
+			want:     plan{State: "unassigning", Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "expired is unassigned",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil), Expired: true},
+			want:     plan{State: "unassigning", Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "expired is not assigned",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), Expired: true},
+			want:     plan{},
+		},
+		{
+			name:     "expired keeps unassigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("unassigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), Expired: true},
+			want:     plan{Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "not allocated over quota",
+			observed: observedState{HasFinalizer: true, QuotaExceeded: true},
+			want:     plan{},
+		},
+		{
+			name:     "not assigned over quota",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), QuotaExceeded: true},
+			want:     plan{},
+		},
+		{
+			name:     "unassigned over quota",
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil), QuotaExceeded: true},
+			want:     plan{State: "unassigning", Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "tags are updated while violating a policy",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment, Tags: &tags},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), PolicyViolation: true},
+			want:     plan{Actions: []action{actionUpdateTags}},
+		},
+		{
+			name:     "allocated without the cluster tag",
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil), MissingClusterTag: true},
+			want:     plan{Actions: []action{actionUpdateTags}},
+		},
+		{
+			name:     "allocated with matching tags",
+			spec:     ociv1alpha1.ReservedIPSpec{Tags: &tags},
+			status:   withState("allocated"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", map[string]string{"team": "net"})},
+			want:     plan{},
+		},
+		{
+			name:     "assigning retries assignment",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("assigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{Actions: []action{actionAssign}},
+		},
+		{
+			name:     "assigning with removed assignment goes back to allocated",
+			status:   withState("assigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{State: "allocated"},
+		},
+		{
+			name:     "assigned and in sync",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{},
+		},
+		{
+			name:     "assigned with removed assignment starts unassigning",
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{State: "unassigning", Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "assigned with changed pod starts reassigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-b"}},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{State: "reassigning", Actions: []action{actionAssign}},
+		},
+		{
+			name:     "assigned but unassigned in OCI starts reassigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{State: "reassigning", Actions: []action{actionAssign}},
+		},
+		{
+			name:     "assigned but moved in OCI starts reassigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("ocid1.privateip.oc1..other", nil)},
+			want:     plan{State: "reassigning", Actions: []action{actionAssign}},
+		},
+		{
+			name:     "assigned without status assignment starts reassigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("assigned"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP("", nil)},
+			want:     plan{State: "reassigning", Actions: []action{actionAssign}},
+		},
+		{
+			name:     "assigned to lease holder",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: leaseAssignment},
+			status:   assignedToLeader,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil), LeaseHolder: "pod-a"},
+			want:     plan{},
+		},
+		{
+			name:     "lease holder changed starts reassigning",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: leaseAssignment},
+			status:   assignedToLeader,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil), LeaseHolder: "pod-b"},
+			want: plan{State: "reassigning", Actions: []action{actionAssign},
+				EventReason: "LeaderChanged", EventMessage: "Lease leader is now held by pod pod-b"},
+		},
+		{
+			name:     "assigned with changed tags and assignment",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PrivateIPAddress: "10.0.0.2"}, Tags: &tags},
+			status:   assigned,
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{State: "reassigning", Actions: []action{actionUpdateTags, actionAssign}},
+		},
+		{
+			name:     "reassigning retries assignment",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   withState("reassigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{Actions: []action{actionAssign}},
+		},
+		{
+			name:     "reassigning with removed assignment starts unassigning",
+			status:   withState("reassigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{State: "unassigning", Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "unassigning retries unassignment",
+			status:   withState("unassigning"),
+			observed: observedState{HasFinalizer: true, PublicIP: publicIP(testPrivateIPID, nil)},
+			want:     plan{Actions: []action{actionUnassign}},
+		},
+		{
+			name:     "deleted without finalizer",
+			status:   withState("allocated"),
+			observed: observedState{Deleting: true},
+			want:     plan{},
+		},
+		{
+			name:     "deleted before allocation",
+			observed: observedState{Deleting: true, HasFinalizer: true},
+			want:     plan{Actions: []action{actionRemoveFinalizer}},
+		},
+		{
+			name:     "deleted while assigned starts releasing",
+			spec:     ociv1alpha1.ReservedIPSpec{Assignment: podAssignment},
+			status:   assigned,
+			observed: observedState{Deleting: true, HasFinalizer: true},
+			want:     plan{State: "releasing", Actions: []action{actionRelease, actionRemoveFinalizer}},
+		},
+		{
+			name:     "releasing retries release",
+			status:   withState("releasing"),
+			observed: observedState{Deleting: true, HasFinalizer: true},
+			want:     plan{Actions: []action{actionRelease, actionRemoveFinalizer}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := transition(tt.spec, tt.status, tt.observed)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+var reservedIPStates = []string{"", "allocating", "allocated", "assigning", "assigned", "reassigning", "unassigning", "releasing"}
+
+func FuzzTransition(f *testing.F) {
+	f.Add(uint8(0), false, false, false, "", "", "", "", "", "", "")
+	f.Add(uint8(4), false, true, true, "pod-a", "", "", "pod-a", "", testPrivateIPID, testPrivateIPID)
+	f.Add(uint8(4), false, true, true, "", "", "leader", "pod-a", "pod-b", testPrivateIPID, testPrivateIPID)
+	f.Add(uint8(2), true, true, true, "pod-a", "", "", "", "", "", "")
+
+	f.Fuzz(func(t *testing.T, state uint8, deleting, hasFinalizer, observedIP bool,
+		podName, privateIP, leaseName, statusPodName, leaseHolder, assignedTo, privateIPID string) {
+		spec := ociv1alpha1.ReservedIPSpec{}
+		if podName != "" || privateIP != "" || leaseName != "" {
+			spec.Assignment = &ociv1alpha1.ReservedIPAssignment{PodName: podName, PrivateIPAddress: privateIP, LeaseName: leaseName}
+		}
+		status := ociv1alpha1.ReservedIPStatus{
+			State:              reservedIPStates[int(state)%len(reservedIPStates)],
+			PrivateIPAddressID: privateIPID,
+		}
+		if status.State != "" && status.State != "allocating" {
+			status.OCID = testOCID
+		}
+		if statusPodName != "" {
+			status.Assignment = &ociv1alpha1.ReservedIPAssignment{PodName: statusPodName, LeaseName: leaseName}
+		}
+		observed := observedState{Deleting: deleting, HasFinalizer: hasFinalizer, LeaseHolder: leaseHolder}
+		if observedIP {
+			observed.PublicIP = publicIP(assignedTo, nil)
+		}
+
+		p := transition(spec, status, observed)
+
+		if p.State != "" && !containsString(reservedIPStates[1:], p.State) {
+			t.Fatalf("unknown state %q", p.State)
+		}
+		if p.State != "" && p.State == status.State {
+			t.Fatalf("state change to the current state %q", p.State)
+		}
+		if !hasFinalizer && len(p.Actions) > 0 && !reflect.DeepEqual(p.Actions, []action{actionAddFinalizer}) {
+			t.Fatalf("actions %v without finalizer", p.Actions)
+		}
+		for i, a := range p.Actions {
+			switch a {
+			case actionRelease:
+				if !deleting || status.OCID == "" {
+					t.Fatalf("release of ReservedIP that isn't deleted or allocated: %+v", p)
+				}
+			case actionRemoveFinalizer:
+				if !deleting || i != len(p.Actions)-1 {
+					t.Fatalf("finalizer removed before the end or without deletion: %+v", p)
+				}
+			case actionAssign:
+				if deleting || !hasAssignmentTarget(spec) {
+					t.Fatalf("assignment without target: %+v", p)
+				}
+			case actionUnassign, actionUpdateTags:
+				if deleting || observed.PublicIP == nil {
+					t.Fatalf("%s without observed public IP: %+v", a, p)
+				}
+			}
+		}
+		if deleting && hasFinalizer && (len(p.Actions) == 0 || p.Actions[len(p.Actions)-1] != actionRemoveFinalizer) {
+			t.Fatalf("deleted ReservedIP keeps its finalizer: %+v", p)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// ReservedIPValidator rejects ReservedIPs violating a ReservedIPPolicy,
+// exceeding a ReservedIPQuota or referencing an OCIAccount they may not use.
+// It reserves the quota of the ReservedIPs it admits.
+type ReservedIPValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-oci-k8s-logmein-com-v1alpha1-reservedip,mutating=false,failurePolicy=fail,sideEffects=NoneOnDryRun,groups=oci.k8s.logmein.com,resources=reservedips,verbs=create;update,versions=v1alpha1,name=vreservedip.oci.k8s.logmein.com,admissionReviewVersions=v1
+
+func (v *ReservedIPValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var reservedIP ociv1alpha1.ReservedIP
+	if err := v.decoder.DecodeRaw(req.Object, &reservedIP); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var old *ociv1alpha1.ReservedIP
+	if req.Operation == admissionv1.Update {
+		old = &ociv1alpha1.ReservedIP{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if reflect.DeepEqual(old.Spec, reservedIP.Spec) || onlyNarrowsAssignment(&old.Spec, &reservedIP.Spec) {
+			// don't block removing finalizers, annotations or assignments of
+			// ReservedIPs that were created before a policy or quota, as the
+			// operator does when unassigning or deleting them
+			return admission.Allowed("")
+		}
+	}
+
+	if err := v.validate(ctx, old, &reservedIP, req.DryRun != nil && *req.DryRun); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// onlyNarrowsAssignment reports whether an update of the spec of a ReservedIP
+// only removes its assignment or allowTakeover.
+func onlyNarrowsAssignment(old, spec *ociv1alpha1.ReservedIPSpec) bool {
+	narrowed := *old
+	switch {
+	case old.Assignment == nil:
+		return false
+	case spec.Assignment == nil:
+		narrowed.Assignment = nil
+	case old.Assignment.AllowTakeover && !spec.Assignment.AllowTakeover:
+		assignment := *old.Assignment
+		assignment.AllowTakeover = false
+		narrowed.Assignment = &assignment
+	default:
+		return false
+	}
+	return reflect.DeepEqual(&narrowed, spec)
+}
+
+// validate checks a created ReservedIP, or, on updates, only what the update
+// changes: ReservedIPs that already violate a policy, e.g. because the policy
+// was created or their namespace relabelled after them, can still be updated
+// as long as the update doesn't add violations.
+func (v *ReservedIPValidator) validate(ctx context.Context, old, reservedIP *ociv1alpha1.ReservedIP, dryRun bool) error {
+	if old == nil || old.Spec.AccountName != reservedIP.Spec.AccountName {
+		if err := checkAccount(ctx, v.Client, reservedIP.Namespace, &reservedIP.Spec); err != nil {
+			return err
+		}
+	}
+
+	violations, err := checkPolicies(ctx, v.Client, reservedIP.Namespace, &reservedIP.Spec)
+	if err != nil {
+		return err
+	}
+	if old != nil && len(violations) > 0 {
+		oldViolations, err := checkPolicies(ctx, v.Client, old.Namespace, &old.Spec)
+		if err != nil {
+			return err
+		}
+		violations = addedViolations(oldViolations, violations)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("ReservedIP violates ReservedIPPolicies: %s", strings.Join(violations, "; "))
+	}
+
+	// last, as it reserves the quota
+	exceeded, err := checkQuotas(ctx, v.Client, old, reservedIP, dryRun)
+	if err != nil {
+		return err
+	}
+	if len(exceeded) > 0 {
+		return fmt.Errorf("ReservedIP exceeds quota: %s", strings.Join(exceeded, "; "))
+	}
+	return nil
+}
+
+// addedViolations returns the violations that aren't in old.
+func addedViolations(old, violations []string) []string {
+	var added []string
+	for _, violation := range violations {
+		if !containsString(old, violation) {
+			added = append(added, violation)
+		}
+	}
+	return added
+}
+
+func (v *ReservedIPValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	v.decoder = decoder
+	mgr.GetWebhookServer().Register("/validate-oci-k8s-logmein-com-v1alpha1-reservedip", &webhook.Admission{Handler: v})
+	return nil
+}
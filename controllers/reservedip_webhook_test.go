@@ -0,0 +1,173 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestOnlyNarrowsAssignment(t *testing.T) {
+	pod := &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}
+	takeover := &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a", AllowTakeover: true}
+
+	tests := []struct {
+		name string
+		old  ociv1alpha1.ReservedIPSpec
+		spec ociv1alpha1.ReservedIPSpec
+		want bool
+	}{
+		{
+			name: "unassign",
+			old:  ociv1alpha1.ReservedIPSpec{Assignment: pod},
+			want: true,
+		},
+		{
+			name: "drop allowTakeover",
+			old:  ociv1alpha1.ReservedIPSpec{Assignment: takeover},
+			spec: ociv1alpha1.ReservedIPSpec{Assignment: pod},
+			want: true,
+		},
+		{
+			name: "unassign and change the pool",
+			old:  ociv1alpha1.ReservedIPSpec{Assignment: pod},
+			spec: ociv1alpha1.ReservedIPSpec{PublicIPPoolID: "ocid1.publicippool.oc1..a"},
+		},
+		{
+			name: "assign",
+			spec: ociv1alpha1.ReservedIPSpec{Assignment: pod},
+		},
+		{
+			name: "reassign",
+			old:  ociv1alpha1.ReservedIPSpec{Assignment: pod},
+			spec: ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-b"}},
+		},
+		{
+			name: "allow takeover",
+			old:  ociv1alpha1.ReservedIPSpec{Assignment: pod},
+			spec: ociv1alpha1.ReservedIPSpec{Assignment: takeover},
+		},
+		{
+			name: "unassigned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := onlyNarrowsAssignment(&tt.old, &tt.spec); got != tt.want {
+				t.Errorf("onlyNarrowsAssignment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReservedIPValidatorUpdate(t *testing.T) {
+	// forbids Lease assignments and the pool b, but not the pool c
+	policy := &ociv1alpha1.ReservedIPPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "p"},
+		Spec: ociv1alpha1.ReservedIPPolicySpec{
+			AllowedAssignmentTypes: []ociv1alpha1.ReservedIPAssignmentType{ociv1alpha1.AssignmentTypePod},
+			AllowedPublicIPPoolIDs: []string{"ocid1.publicippool.oc1..a", "ocid1.publicippool.oc1..c"},
+		},
+	}
+	lease := &ociv1alpha1.ReservedIPAssignment{LeaseName: "leader"}
+
+	tests := []struct {
+		name    string
+		old     ociv1alpha1.ReservedIPSpec
+		spec    ociv1alpha1.ReservedIPSpec
+		allowed bool
+	}{
+		{
+			name:    "unassigning a violating ReservedIP",
+			old:     ociv1alpha1.ReservedIPSpec{Assignment: lease, PublicIPPoolID: "ocid1.publicippool.oc1..b"},
+			spec:    ociv1alpha1.ReservedIPSpec{PublicIPPoolID: "ocid1.publicippool.oc1..b"},
+			allowed: true,
+		},
+		{
+			name:    "changing a violating ReservedIP without adding violations",
+			old:     ociv1alpha1.ReservedIPSpec{Assignment: lease, PublicIPPoolID: "ocid1.publicippool.oc1..a"},
+			spec:    ociv1alpha1.ReservedIPSpec{Assignment: lease, PublicIPPoolID: "ocid1.publicippool.oc1..c"},
+			allowed: true,
+		},
+		{
+			name: "adding a violation",
+			old:  ociv1alpha1.ReservedIPSpec{Assignment: lease, PublicIPPoolID: "ocid1.publicippool.oc1..a"},
+			spec: ociv1alpha1.ReservedIPSpec{Assignment: lease, PublicIPPoolID: "ocid1.publicippool.oc1..b"},
+		},
+		{
+			name: "assigning against the policy",
+			spec: ociv1alpha1.ReservedIPSpec{Assignment: lease},
+		},
+		{
+			name:    "assigning",
+			spec:    ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}},
+			allowed: true,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := func(spec ociv1alpha1.ReservedIPSpec) runtime.RawExtension {
+		data, err := json.Marshal(&ociv1alpha1.ReservedIP{
+			TypeMeta:   metav1.TypeMeta{APIVersion: ociv1alpha1.GroupVersion.String(), Kind: "ReservedIP"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"},
+			Spec:       spec,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				policy,
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			).Build()
+			v := &ReservedIPValidator{Client: c, decoder: decoder}
+
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				Object:    raw(tt.spec),
+				OldObject: raw(tt.old),
+			}}
+			resp := v.Handle(context.Background(), req)
+			if resp.Allowed != tt.allowed {
+				t.Errorf("Handle() allowed = %v (%s), want %v", resp.Allowed, resp.Result.Message, tt.allowed)
+			}
+		})
+	}
+}
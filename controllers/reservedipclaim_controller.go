@@ -0,0 +1,300 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// ReservedIPClaimReconciler reconciles a ReservedIPClaim object by binding it
+// to an existing ReservedIP or provisioning a new one from a ReservedIPClass.
+type ReservedIPClaimReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipclaims,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipclaims/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipclasses,verbs=get;list;watch
+
+func (r *ReservedIPClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("reservedIPClaim", req.NamespacedName)
+
+	var claim ociv1alpha1.ReservedIPClaim
+	if err := r.Get(ctx, req.NamespacedName, &claim); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	res, err := r.handleRequest(ctx, &claim, log)
+	if err != nil {
+		r.Recorder.Event(&claim, "Warning", "ReconcileError", err.Error())
+	}
+	return res, err
+}
+
+func (r *ReservedIPClaimReconciler) handleRequest(ctx context.Context, claim *ociv1alpha1.ReservedIPClaim, log logr.Logger) (ctrl.Result, error) {
+	if !claim.ObjectMeta.DeletionTimestamp.IsZero() {
+		if containsString(claim.ObjectMeta.Finalizers, finalizerName) {
+			if err := r.reclaimReservedIP(ctx, claim, log); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// remove finalizer, allow k8s to remove the resource
+			return ctrl.Result{}, removeFinalizer(ctx, r.Client, claim)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !containsString(claim.ObjectMeta.Finalizers, finalizerName) {
+		return ctrl.Result{}, addFinalizer(ctx, r.Client, claim)
+	}
+
+	base := claim.DeepCopy()
+	if claim.Status.ReservedIPName == "" {
+		if err := r.bindReservedIP(ctx, claim, log); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if claim.Status.ReservedIPName != "" {
+		var reservedIP ociv1alpha1.ReservedIP
+		err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: claim.Status.ReservedIPName}, &reservedIP)
+		if apierrors.IsNotFound(err) {
+			claim.Status.Phase = "Lost"
+			claim.Status.Message = fmt.Sprintf("ReservedIP %s doesn't exist anymore", claim.Status.ReservedIPName)
+			claim.Status.PublicIPAddress = ""
+		} else if err != nil {
+			return ctrl.Result{}, err
+		} else {
+			claim.Status.Phase = "Bound"
+			claim.Status.Message = ""
+			claim.Status.PublicIPAddress = reservedIP.Status.PublicIPAddress
+		}
+	}
+
+	if base.Status != claim.Status {
+		return ctrl.Result{}, r.Status().Patch(ctx, claim, client.MergeFrom(base), fieldOwner)
+	}
+	return ctrl.Result{}, nil
+}
+
+// bindReservedIP binds the claim to the ReservedIP given in the spec or
+// provisions a new ReservedIP from the claim's class. If neither is possible,
+// the claim stays pending.
+func (r *ReservedIPClaimReconciler) bindReservedIP(ctx context.Context, claim *ociv1alpha1.ReservedIPClaim, log logr.Logger) error {
+	claim.Status.Phase = "Pending"
+
+	if claim.Spec.ReservedIPName != "" {
+		var reservedIP ociv1alpha1.ReservedIP
+		if err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: claim.Spec.ReservedIPName}, &reservedIP); err != nil {
+			if apierrors.IsNotFound(err) {
+				claim.Status.Message = fmt.Sprintf("ReservedIP %s not found", claim.Spec.ReservedIPName)
+				return nil
+			}
+			return err
+		}
+
+		if boundClaim, ok := reservedIP.Annotations[ociv1alpha1.BoundClaimAnnotation]; ok && boundClaim != claim.Name {
+			claim.Status.Message = fmt.Sprintf("ReservedIP %s is already bound to ReservedIPClaim %s", reservedIP.Name, boundClaim)
+			return nil
+		}
+
+		patch := lockedMergeFrom(&reservedIP)
+		if reservedIP.Annotations == nil {
+			reservedIP.Annotations = map[string]string{}
+		}
+		reservedIP.Annotations[ociv1alpha1.BoundClaimAnnotation] = claim.Name
+		if err := r.Patch(ctx, &reservedIP, patch, fieldOwner); err != nil {
+			return err
+		}
+
+		log.Info("bound to existing ReservedIP", "reservedIP", reservedIP.Name)
+		r.Recorder.Event(claim, "Normal", "Bound", fmt.Sprintf("Bound to ReservedIP %s", reservedIP.Name))
+		claim.Status.ReservedIPName = reservedIP.Name
+		return nil
+	}
+
+	class, err := r.getClass(ctx, claim.Spec.ReservedIPClassName)
+	if err != nil {
+		return err
+	}
+	if class == nil {
+		if claim.Spec.ReservedIPClassName == "" {
+			claim.Status.Message = "no default ReservedIPClass found"
+		} else {
+			claim.Status.Message = fmt.Sprintf("ReservedIPClass %s not found", claim.Spec.ReservedIPClassName)
+		}
+		return nil
+	}
+
+	reclaimPolicy := class.Spec.ReclaimPolicy
+	if reclaimPolicy == "" {
+		reclaimPolicy = ociv1alpha1.ReservedIPReclaimDelete
+	}
+	reservedIP := &ociv1alpha1.ReservedIP{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: claim.Namespace,
+			Name:      provisionedReservedIPName(claim),
+			Labels: map[string]string{
+				ociv1alpha1.ProvisionedForClaimLabel: string(claim.UID),
+			},
+			Annotations: map[string]string{
+				ociv1alpha1.BoundClaimAnnotation: claim.Name,
+			},
+		},
+		Spec: ociv1alpha1.ReservedIPSpec{
+			PublicIPPoolID: class.Spec.PublicIPPoolID,
+			CompartmentID:  class.Spec.CompartmentID,
+			AccountName:    class.Spec.AccountName,
+			ClassName:      class.Name,
+			ReclaimPolicy:  reclaimPolicy,
+		},
+	}
+	if class.Spec.Tags != nil {
+		tags := make(map[string]string, len(class.Spec.Tags))
+		for k, v := range class.Spec.Tags {
+			tags[k] = v
+		}
+		reservedIP.Spec.Tags = &tags
+	}
+
+	if err := r.Create(ctx, reservedIP); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	log.Info("provisioned ReservedIP", "reservedIP", reservedIP.Name, "class", class.Name)
+	r.Recorder.Event(claim, "Normal", "Provisioned", fmt.Sprintf("Provisioned ReservedIP %s from ReservedIPClass %s", reservedIP.Name, class.Name))
+	claim.Status.ReservedIPName = reservedIP.Name
+	return nil
+}
+
+// getClass returns the ReservedIPClass with the given name or the default
+// class if name is empty. It returns nil if no matching class exists.
+func (r *ReservedIPClaimReconciler) getClass(ctx context.Context, name string) (*ociv1alpha1.ReservedIPClass, error) {
+	if name != "" {
+		var class ociv1alpha1.ReservedIPClass
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, &class); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &class, nil
+	}
+
+	var classes ociv1alpha1.ReservedIPClassList
+	if err := r.List(ctx, &classes); err != nil {
+		return nil, err
+	}
+	var defaultClass *ociv1alpha1.ReservedIPClass
+	for i := range classes.Items {
+		if !classes.Items[i].IsDefault() {
+			continue
+		}
+		if defaultClass != nil {
+			return nil, fmt.Errorf("more than one default ReservedIPClass found: %s, %s", defaultClass.Name, classes.Items[i].Name)
+		}
+		defaultClass = &classes.Items[i]
+	}
+	return defaultClass, nil
+}
+
+// reclaimReservedIP applies the reclaim policy of the bound ReservedIP when the
+// claim is deleted.
+func (r *ReservedIPClaimReconciler) reclaimReservedIP(ctx context.Context, claim *ociv1alpha1.ReservedIPClaim, log logr.Logger) error {
+	name := claim.Status.ReservedIPName
+	if name == "" && claim.Spec.ReservedIPName == "" {
+		// the provisioned ReservedIP might not have made it to the status
+		name = provisionedReservedIPName(claim)
+	}
+	if name == "" {
+		return nil
+	}
+
+	var reservedIP ociv1alpha1.ReservedIP
+	if err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: name}, &reservedIP); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if reservedIP.Annotations[ociv1alpha1.BoundClaimAnnotation] != claim.Name {
+		return nil
+	}
+
+	if !reclaimDeletes(claim, &reservedIP) {
+		log.Info("releasing ReservedIP from claim", "reservedIP", reservedIP.Name)
+		patch := lockedMergeFrom(&reservedIP)
+		delete(reservedIP.Annotations, ociv1alpha1.BoundClaimAnnotation)
+		delete(reservedIP.Labels, ociv1alpha1.ProvisionedForClaimLabel)
+		return r.Patch(ctx, &reservedIP, patch, fieldOwner)
+	}
+
+	log.Info("deleting ReservedIP of claim", "reservedIP", reservedIP.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, &reservedIP))
+}
+
+func provisionedReservedIPName(claim *ociv1alpha1.ReservedIPClaim) string {
+	return fmt.Sprintf("claim-%s", claim.UID)
+}
+
+// reclaimDeletes tells whether the ReservedIP bound to the claim is deleted
+// with it. ReservedIPs that weren't provisioned for the claim, e.g. bound via
+// spec.reservedIPName, are never deleted, whatever their class and reclaim
+// policy.
+func reclaimDeletes(claim *ociv1alpha1.ReservedIPClaim, reservedIP *ociv1alpha1.ReservedIP) bool {
+	return reservedIP.Labels[ociv1alpha1.ProvisionedForClaimLabel] == string(claim.UID) &&
+		reservedIP.Spec.ReclaimPolicy != ociv1alpha1.ReservedIPReclaimRetain
+}
+
+func (r *ReservedIPClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ociv1alpha1.ReservedIPClaim{}).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIP{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			claimName, ok := obj.GetAnnotations()[ociv1alpha1.BoundClaimAnnotation]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: claimName}}}
+		})).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIPClass{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			// pending claims might be waiting for this class
+			var claims ociv1alpha1.ReservedIPClaimList
+			if err := mgr.GetClient().List(context.Background(), &claims); err != nil {
+				return nil
+			}
+			var requests []reconcile.Request
+			for _, claim := range claims.Items {
+				if claim.Status.ReservedIPName == "" {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name}})
+				}
+			}
+			return requests
+		})).
+		Complete(r)
+}
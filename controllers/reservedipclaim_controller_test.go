@@ -0,0 +1,154 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestReclaimReservedIP(t *testing.T) {
+	// claimed returns a ReservedIP of the byoip class bound to my-claim,
+	// provisioned for the claim with the given UID if it isn't empty
+	claimed := func(name, provisionedFor string, policy ociv1alpha1.ReservedIPReclaimPolicy) *ociv1alpha1.ReservedIP {
+		reservedIP := testReservedIP(name, nil)
+		reservedIP.Annotations = map[string]string{ociv1alpha1.BoundClaimAnnotation: "my-claim"}
+		if provisionedFor != "" {
+			reservedIP.Labels = map[string]string{ociv1alpha1.ProvisionedForClaimLabel: provisionedFor}
+		}
+		reservedIP.Spec.ClassName = "byoip"
+		reservedIP.Spec.ReclaimPolicy = policy
+		return reservedIP
+	}
+
+	tests := []struct {
+		name        string
+		spec        ociv1alpha1.ReservedIPClaimSpec
+		bound       string
+		reservedIP  *ociv1alpha1.ReservedIP
+		wantDeleted bool
+	}{
+		{
+			name:        "deletes a provisioned ReservedIP",
+			bound:       "claim-uid-1",
+			reservedIP:  claimed("claim-uid-1", "uid-1", ociv1alpha1.ReservedIPReclaimDelete),
+			wantDeleted: true,
+		},
+		{
+			name:       "keeps a provisioned ReservedIP with the Retain policy",
+			bound:      "claim-uid-1",
+			reservedIP: claimed("claim-uid-1", "uid-1", ociv1alpha1.ReservedIPReclaimRetain),
+		},
+		{
+			name:       "keeps an existing ReservedIP of a class",
+			spec:       ociv1alpha1.ReservedIPClaimSpec{ReservedIPName: "existing"},
+			bound:      "existing",
+			reservedIP: claimed("existing", "", ociv1alpha1.ReservedIPReclaimDelete),
+		},
+		{
+			name:       "keeps a ReservedIP provisioned for another claim",
+			spec:       ociv1alpha1.ReservedIPClaimSpec{ReservedIPName: "claim-uid-0"},
+			bound:      "claim-uid-0",
+			reservedIP: claimed("claim-uid-0", "uid-0", ociv1alpha1.ReservedIPReclaimDelete),
+		},
+		{
+			name:        "deletes a provisioned ReservedIP missing from the status",
+			reservedIP:  claimed("claim-uid-1", "uid-1", ociv1alpha1.ReservedIPReclaimDelete),
+			wantDeleted: true,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := &ociv1alpha1.ReservedIPClaim{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "my-claim", UID: "uid-1"},
+				Spec:       tt.spec,
+				Status:     ociv1alpha1.ReservedIPClaimStatus{ReservedIPName: tt.bound},
+			}
+			r := &ReservedIPClaimReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.reservedIP).Build(),
+				Log:      logr.Discard(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			ctx := context.Background()
+			if err := r.reclaimReservedIP(ctx, claim, logr.Discard()); err != nil {
+				t.Fatal(err)
+			}
+
+			var got ociv1alpha1.ReservedIP
+			err := r.Get(ctx, client.ObjectKeyFromObject(tt.reservedIP), &got)
+			if tt.wantDeleted {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("ReservedIP not deleted: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReservedIP deleted: %v", err)
+			}
+			if claimName, ok := got.Annotations[ociv1alpha1.BoundClaimAnnotation]; ok {
+				t.Errorf("ReservedIP still bound to %q", claimName)
+			}
+		})
+	}
+}
+
+func TestBindReservedIPProvisions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	class := &ociv1alpha1.ReservedIPClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "byoip"},
+		Spec:       ociv1alpha1.ReservedIPClassSpec{PublicIPPoolID: "ocid1.publicippool.oc1..a"},
+	}
+	r := &ReservedIPClaimReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(class).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+	claim := &ociv1alpha1.ReservedIPClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "my-claim", UID: "uid-1"},
+		Spec:       ociv1alpha1.ReservedIPClaimSpec{ReservedIPClassName: "byoip"},
+	}
+	ctx := context.Background()
+	if err := r.bindReservedIP(ctx, claim, logr.Discard()); err != nil {
+		t.Fatal(err)
+	}
+
+	var reservedIP ociv1alpha1.ReservedIP
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: claim.Status.ReservedIPName}, &reservedIP); err != nil {
+		t.Fatal(err)
+	}
+	if !reclaimDeletes(claim, &reservedIP) {
+		t.Errorf("provisioned ReservedIP wouldn't be deleted with the claim: labels %v, reclaimPolicy %q", reservedIP.Labels, reservedIP.Spec.ReclaimPolicy)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// ReservedIPFailoverReconciler reconciles a ReservedIPFailover object by
+// keeping its ReservedIP assigned to a healthy pod. Moving the ReservedIP is
+// done by changing spec.assignment, which makes the ReservedIPReconciler go
+// through the reassigning state.
+type ReservedIPFailoverReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipfailovers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipfailovers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+func (r *ReservedIPFailoverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("reservedIPFailover", req.NamespacedName)
+
+	var failover ociv1alpha1.ReservedIPFailover
+	if err := r.Get(ctx, req.NamespacedName, &failover); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	res, err := r.handleRequest(ctx, &failover, log)
+	if err != nil {
+		r.Recorder.Event(&failover, "Warning", "ReconcileError", err.Error())
+	}
+	return res, err
+}
+
+func (r *ReservedIPFailoverReconciler) handleRequest(ctx context.Context, failover *ociv1alpha1.ReservedIPFailover, log logr.Logger) (ctrl.Result, error) {
+	var reservedIP ociv1alpha1.ReservedIP
+	err := r.Get(ctx, client.ObjectKey{Namespace: failover.Namespace, Name: failover.Spec.ReservedIPName}, &reservedIP)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+
+	if !failover.ObjectMeta.DeletionTimestamp.IsZero() {
+		if containsString(failover.ObjectMeta.Finalizers, finalizerName) {
+			if found && reservedIP.Annotations[ociv1alpha1.FailoverAnnotation] == failover.Name {
+				log.Info("unassigning ReservedIP")
+				patch := lockedMergeFrom(&reservedIP)
+				reservedIP.Spec.Assignment = nil
+				delete(reservedIP.Annotations, ociv1alpha1.FailoverAnnotation)
+				if err := r.Patch(ctx, &reservedIP, patch, fieldOwner); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+
+			// remove finalizer, allow k8s to remove the resource
+			return ctrl.Result{}, removeFinalizer(ctx, r.Client, failover)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !containsString(failover.ObjectMeta.Finalizers, finalizerName) {
+		return ctrl.Result{}, addFinalizer(ctx, r.Client, failover)
+	}
+
+	base := failover.DeepCopy()
+	if err := r.failover(ctx, failover, found, &reservedIP, log); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reflect.DeepEqual(base.Status, failover.Status) {
+		return ctrl.Result{}, r.Status().Patch(ctx, failover, client.MergeFrom(base), fieldOwner)
+	}
+	return ctrl.Result{}, nil
+}
+
+// failover moves the ReservedIP to the pod chosen by the failover policy.
+func (r *ReservedIPFailoverReconciler) failover(ctx context.Context, failover *ociv1alpha1.ReservedIPFailover, found bool, reservedIP *ociv1alpha1.ReservedIP, log logr.Logger) error {
+	if !found {
+		failover.Status.ActivePod = ""
+		failover.Status.Message = fmt.Sprintf("ReservedIP %s not found", failover.Spec.ReservedIPName)
+		return nil
+	}
+
+	if owner := reservedIP.Annotations[ociv1alpha1.AssociationAnnotation]; owner != "" {
+		failover.Status.Message = fmt.Sprintf("ReservedIP %s is already assigned by ReservedIPAssociation %s", reservedIP.Name, owner)
+		return nil
+	}
+	if owner := reservedIP.Annotations[ociv1alpha1.FailoverAnnotation]; owner != "" && owner != failover.Name {
+		failover.Status.Message = fmt.Sprintf("ReservedIP %s is already managed by ReservedIPFailover %s", reservedIP.Name, owner)
+		return nil
+	}
+	if reservedIP.Annotations[ociv1alpha1.FailoverAnnotation] == "" && reservedIP.Spec.Assignment != nil {
+		// don't take over an assignment made by hand; it must be removed first
+		failover.Status.ActivePod = ""
+		failover.Status.Message = fmt.Sprintf("ReservedIP %s is already assigned; remove its spec.assignment to let the ReservedIPFailover manage it", reservedIP.Name)
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&failover.Spec.PodSelector)
+	if err != nil {
+		failover.Status.Message = fmt.Sprintf("invalid spec.podSelector: %s", err)
+		return nil
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(failover.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+
+	current := ""
+	if reservedIP.Annotations[ociv1alpha1.FailoverAnnotation] == failover.Name && reservedIP.Spec.Assignment != nil {
+		current = reservedIP.Spec.Assignment.PodName
+	}
+	target := chooseFailoverPod(failover.Spec.Policy, failover.Spec.Priority, current, pods.Items)
+
+	failover.Status.Message = ""
+	if target == "" {
+		failover.Status.Message = "no Ready pod matches spec.podSelector"
+	}
+	if target == current && reservedIP.Annotations[ociv1alpha1.FailoverAnnotation] == failover.Name {
+		failover.Status.ActivePod = current
+		return nil
+	}
+
+	patch := lockedMergeFrom(reservedIP)
+	if target == "" {
+		log.Info("no Ready pod left; unassigning ReservedIP", "previousPod", current)
+		reservedIP.Spec.Assignment = nil
+	} else {
+		log.Info("moving ReservedIP", "previousPod", current, "pod", target)
+		reservedIP.Spec.Assignment = &ociv1alpha1.ReservedIPAssignment{PodName: target}
+	}
+	if reservedIP.Annotations == nil {
+		reservedIP.Annotations = map[string]string{}
+	}
+	reservedIP.Annotations[ociv1alpha1.FailoverAnnotation] = failover.Name
+	if err := r.Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return err
+	}
+
+	if current != target {
+		r.Recorder.Event(failover, "Normal", "Failover", fmt.Sprintf("Moved ReservedIP %s from pod %q to pod %q", reservedIP.Name, current, target))
+		now := metav1.Now()
+		failover.Status.LastFailoverTime = &now
+	}
+	failover.Status.ActivePod = target
+	return nil
+}
+
+// chooseFailoverPod returns the pod the ReservedIP should be assigned to, given
+// the currently active pod and the candidate pods. It returns an empty string
+// if there is no suitable pod.
+func chooseFailoverPod(policy ociv1alpha1.FailoverPolicy, priority []string, current string, pods []corev1.Pod) string {
+	var ready []corev1.Pod
+	currentAlive, currentReady := false, false
+	for _, pod := range pods {
+		alive := pod.DeletionTimestamp.IsZero() && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+		if pod.Name == current {
+			currentAlive = alive
+			currentReady = alive && isPodReady(&pod)
+		}
+		if alive && isPodReady(&pod) && pod.Status.PodIP != "" {
+			ready = append(ready, pod)
+		}
+	}
+
+	switch policy {
+	case ociv1alpha1.FailoverSticky:
+		if currentReady || (currentAlive && len(ready) == 0) {
+			return current
+		}
+	case ociv1alpha1.FailoverOrdered:
+		rank := make(map[string]int, len(priority))
+		for i, name := range priority {
+			rank[name] = i
+		}
+		sort.Slice(ready, func(i, j int) bool {
+			ri, iok := rank[ready[i].Name]
+			rj, jok := rank[ready[j].Name]
+			if iok != jok {
+				return iok
+			}
+			if iok && ri != rj {
+				return ri < rj
+			}
+			return ready[i].Name < ready[j].Name
+		})
+		if len(ready) > 0 {
+			return ready[0].Name
+		}
+		return ""
+	default:
+		if currentReady {
+			return current
+		}
+	}
+
+	// oldest Ready pod first, as it is most likely to stay
+	sort.Slice(ready, func(i, j int) bool {
+		if !ready[i].CreationTimestamp.Equal(&ready[j].CreationTimestamp) {
+			return ready[i].CreationTimestamp.Before(&ready[j].CreationTimestamp)
+		}
+		return ready[i].Name < ready[j].Name
+	})
+	if len(ready) > 0 {
+		return ready[0].Name
+	}
+	return ""
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (r *ReservedIPFailoverReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ociv1alpha1.ReservedIPFailover{}, reservedIPNameField, func(obj client.Object) []string {
+		return []string{obj.(*ociv1alpha1.ReservedIPFailover).Spec.ReservedIPName}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ociv1alpha1.ReservedIPFailover{}).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIP{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			var failovers ociv1alpha1.ReservedIPFailoverList
+			if err := mgr.GetClient().List(context.Background(), &failovers,
+				client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{reservedIPNameField: obj.GetName()}); err != nil {
+				return nil
+			}
+			requests := make([]reconcile.Request, 0, len(failovers.Items))
+			for _, failover := range failovers.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: failover.Namespace, Name: failover.Name}})
+			}
+			return requests
+		})).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			var failovers ociv1alpha1.ReservedIPFailoverList
+			if err := mgr.GetClient().List(context.Background(), &failovers, client.InNamespace(obj.GetNamespace())); err != nil {
+				return nil
+			}
+			var requests []reconcile.Request
+			for _, failover := range failovers.Items {
+				selector, err := metav1.LabelSelectorAsSelector(&failover.Spec.PodSelector)
+				if err != nil || !selector.Matches(labels.Set(obj.GetLabels())) {
+					continue
+				}
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: failover.Namespace, Name: failover.Name}})
+			}
+			return requests
+		})).
+		Complete(r)
+}
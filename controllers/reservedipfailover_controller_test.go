@@ -0,0 +1,168 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestChooseFailoverPod(t *testing.T) {
+	// pod returns a running pod created age ago
+	pod := func(name string, age time.Duration, ready bool) corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(testCreated.Add(-age))},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				PodIP:      "10.0.16.5",
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+	terminated := pod("a", 3*time.Hour, false)
+	terminated.Status.Phase = corev1.PodFailed
+	deleting := pod("a", 3*time.Hour, true)
+	deleting.DeletionTimestamp = &testCreated
+	withoutIP := pod("c", 3*time.Hour, true)
+	withoutIP.Status.PodIP = ""
+
+	tests := []struct {
+		name     string
+		policy   ociv1alpha1.FailoverPolicy
+		priority []string
+		current  string
+		pods     []corev1.Pod
+		want     string
+	}{
+		{
+			name: "PreferReady picks the oldest Ready pod",
+			pods: []corev1.Pod{pod("a", time.Hour, true), pod("b", 2*time.Hour, true), pod("c", 3*time.Hour, false)},
+			want: "b",
+		},
+		{
+			name:    "PreferReady keeps the Ready active pod",
+			current: "a",
+			pods:    []corev1.Pod{pod("a", time.Hour, true), pod("b", 2*time.Hour, true)},
+			want:    "a",
+		},
+		{
+			name:    "PreferReady fails over from a NotReady pod",
+			policy:  ociv1alpha1.FailoverPreferReady,
+			current: "a",
+			pods:    []corev1.Pod{pod("a", 2*time.Hour, false), pod("b", time.Hour, true)},
+			want:    "b",
+		},
+		{
+			name:    "PreferReady unassigns without Ready pods",
+			policy:  ociv1alpha1.FailoverPreferReady,
+			current: "a",
+			pods:    []corev1.Pod{pod("a", time.Hour, false)},
+			want:    "",
+		},
+		{
+			name:    "PreferReady fails over from a deleted pod",
+			current: "a",
+			pods:    []corev1.Pod{deleting, pod("b", time.Hour, true)},
+			want:    "b",
+		},
+		{
+			name: "pods without IP are skipped",
+			pods: []corev1.Pod{withoutIP, pod("b", time.Hour, true)},
+			want: "b",
+		},
+		{
+			name:     "Ordered picks the first listed Ready pod",
+			policy:   ociv1alpha1.FailoverOrdered,
+			priority: []string{"c", "b", "a"},
+			current:  "a",
+			pods:     []corev1.Pod{pod("a", time.Hour, true), pod("b", time.Hour, true), pod("c", time.Hour, false)},
+			want:     "b",
+		},
+		{
+			name:     "Ordered falls back to name order",
+			policy:   ociv1alpha1.FailoverOrdered,
+			priority: []string{"z"},
+			pods:     []corev1.Pod{pod("b", time.Hour, true), pod("a", time.Hour, true)},
+			want:     "a",
+		},
+		{
+			name:     "Ordered unassigns without Ready pods",
+			policy:   ociv1alpha1.FailoverOrdered,
+			priority: []string{"a"},
+			current:  "a",
+			pods:     []corev1.Pod{pod("a", time.Hour, false)},
+			want:     "",
+		},
+		{
+			name:    "Sticky keeps the Ready active pod",
+			policy:  ociv1alpha1.FailoverSticky,
+			current: "a",
+			pods:    []corev1.Pod{pod("a", time.Hour, true), pod("b", 2*time.Hour, true)},
+			want:    "a",
+		},
+		{
+			name:    "Sticky fails over from a NotReady pod",
+			policy:  ociv1alpha1.FailoverSticky,
+			current: "a",
+			pods:    []corev1.Pod{pod("a", 2*time.Hour, false), pod("b", time.Hour, true)},
+			want:    "b",
+		},
+		{
+			name:    "Sticky keeps a NotReady pod without Ready pods",
+			policy:  ociv1alpha1.FailoverSticky,
+			current: "a",
+			pods:    []corev1.Pod{pod("a", time.Hour, false), pod("b", time.Hour, false)},
+			want:    "a",
+		},
+		{
+			name:    "Sticky fails over from a terminated pod",
+			policy:  ociv1alpha1.FailoverSticky,
+			current: "a",
+			pods:    []corev1.Pod{terminated, pod("b", time.Hour, true)},
+			want:    "b",
+		},
+		{
+			name:    "Sticky unassigns a terminated pod without Ready pods",
+			policy:  ociv1alpha1.FailoverSticky,
+			current: "a",
+			pods:    []corev1.Pod{terminated},
+			want:    "",
+		},
+		{
+			name:   "Sticky picks the oldest Ready pod first",
+			policy: ociv1alpha1.FailoverSticky,
+			pods:   []corev1.Pod{pod("a", time.Hour, true), pod("b", 2*time.Hour, true)},
+			want:   "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseFailoverPod(tt.policy, tt.priority, tt.current, tt.pods); got != tt.want {
+				t.Errorf("chooseFailoverPod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
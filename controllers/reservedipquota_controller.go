@@ -0,0 +1,331 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+// ReservedIPQuotaReconciler keeps the usage in the status of ReservedIPQuotas
+// up to date. The quotas are enforced by the ReservedIPValidator and the
+// ReservedIPReconciler.
+type ReservedIPQuotaReconciler struct {
+	client.Client
+	Log logr.Logger
+}
+
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipquotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=oci.k8s.logmein.com,resources=reservedipquotas/status,verbs=get;update;patch
+
+func (r *ReservedIPQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("reservedIPQuota", req.NamespacedName)
+
+	var quota ociv1alpha1.ReservedIPQuota
+	if err := r.Get(ctx, req.NamespacedName, &quota); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var reservedIPs ociv1alpha1.ReservedIPList
+	if err := r.List(ctx, &reservedIPs, client.InNamespace(quota.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	used := quotaUsage(&quota.Spec, reservedIPs.Items, "")
+	if used == quota.Status.Used {
+		return ctrl.Result{}, nil
+	}
+	log.Info("usage changed", "reservedIPs", used.ReservedIPs, "assignedReservedIPs", used.AssignedReservedIPs)
+	// fails if the ReservedIPValidator reserved usage since the quota was read
+	patch := lockedMergeFrom(&quota)
+	quota.Status.Used = used
+	return ctrl.Result{}, r.Status().Patch(ctx, &quota, patch, fieldOwner)
+}
+
+// quotaCounts reports whether the quota counts the ReservedIP, and whether it
+// counts it as assigned.
+func quotaCounts(quota *ociv1alpha1.ReservedIPQuotaSpec, spec *ociv1alpha1.ReservedIPSpec) (counted, assigned bool) {
+	if quota.PublicIPPoolID != "" && quota.PublicIPPoolID != spec.PublicIPPoolID {
+		return false, false
+	}
+	return true, spec.Assignment != nil
+}
+
+// quotaUsage counts the ReservedIPs of the quota, except those being deleted
+// and the one named skip.
+func quotaUsage(quota *ociv1alpha1.ReservedIPQuotaSpec, reservedIPs []ociv1alpha1.ReservedIP, skip string) ociv1alpha1.ReservedIPQuotaUsage {
+	var used ociv1alpha1.ReservedIPQuotaUsage
+	for i := range reservedIPs {
+		reservedIP := &reservedIPs[i]
+		if reservedIP.Name == skip || !reservedIP.DeletionTimestamp.IsZero() {
+			continue
+		}
+		counted, assigned := quotaCounts(quota, &reservedIP.Spec)
+		if counted {
+			used.ReservedIPs++
+		}
+		if assigned {
+			used.AssignedReservedIPs++
+		}
+	}
+	return used
+}
+
+// quotaIncrease returns by how much changing a ReservedIP from old, nil if it
+// is created, to spec increases the usage of the quota. Changes that don't add
+// to the usage, like unassigning, don't increase it.
+func quotaIncrease(quota *ociv1alpha1.ReservedIPQuotaSpec, old, spec *ociv1alpha1.ReservedIPSpec) ociv1alpha1.ReservedIPQuotaUsage {
+	var increase ociv1alpha1.ReservedIPQuotaUsage
+	counted, assigned := quotaCounts(quota, spec)
+	var wasCounted, wasAssigned bool
+	if old != nil {
+		wasCounted, wasAssigned = quotaCounts(quota, old)
+	}
+	if counted && !wasCounted {
+		increase.ReservedIPs = 1
+	}
+	if assigned && !wasAssigned {
+		increase.AssignedReservedIPs = 1
+	}
+	return increase
+}
+
+// exceedsQuota returns the limits of the quota that adding increase to used
+// would exceed. Limits that increase doesn't add to are never exceeded, so an
+// exceeded quota doesn't block e.g. unassigning.
+func exceedsQuota(quota *ociv1alpha1.ReservedIPQuota, used, increase ociv1alpha1.ReservedIPQuotaUsage) []string {
+	var exceeded []string
+	if hard := quota.Spec.Hard.ReservedIPs; hard != nil && increase.ReservedIPs > 0 && used.ReservedIPs+increase.ReservedIPs > *hard {
+		exceeded = append(exceeded, fmt.Sprintf("ReservedIPQuota %s: %d of %d ReservedIPs used", quota.Name, used.ReservedIPs, *hard))
+	}
+	if hard := quota.Spec.Hard.AssignedReservedIPs; hard != nil && increase.AssignedReservedIPs > 0 && used.AssignedReservedIPs+increase.AssignedReservedIPs > *hard {
+		exceeded = append(exceeded, fmt.Sprintf("ReservedIPQuota %s: %d of %d assigned ReservedIPs used", quota.Name, used.AssignedReservedIPs, *hard))
+	}
+	return exceeded
+}
+
+// checkQuotas returns the ReservedIPQuotas a ReservedIP would exceed when
+// created or, if old is given, changed from old. If none is exceeded and
+// dryRun isn't set, it adds the ReservedIP to the usage in the status of the
+// quotas, like the admission of ResourceQuotas: the status is updated with
+// optimistic locking, so concurrent requests can't both take the last
+// ReservedIP of a quota. The ReservedIPQuotaReconciler recounts the usage
+// when ReservedIPs change, which also drops reservations of requests rejected
+// afterwards.
+func checkQuotas(ctx context.Context, c client.Client, old, reservedIP *ociv1alpha1.ReservedIP, dryRun bool) ([]string, error) {
+	var quotas ociv1alpha1.ReservedIPQuotaList
+	if err := c.List(ctx, &quotas, client.InNamespace(reservedIP.Namespace)); err != nil {
+		return nil, err
+	}
+	var oldSpec *ociv1alpha1.ReservedIPSpec
+	if old != nil {
+		oldSpec = &old.Spec
+	}
+
+	var exceeded []string
+	increases := make([]ociv1alpha1.ReservedIPQuotaUsage, len(quotas.Items))
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		increases[i] = quotaIncrease(&quota.Spec, oldSpec, &reservedIP.Spec)
+		exceeded = append(exceeded, exceedsQuota(quota, quota.Status.Used, increases[i])...)
+	}
+	if len(exceeded) > 0 || dryRun {
+		return exceeded, nil
+	}
+
+	for i := range quotas.Items {
+		quota, increase := &quotas.Items[i], increases[i]
+		if increase == (ociv1alpha1.ReservedIPQuotaUsage{}) {
+			continue
+		}
+		first := true
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			if !first {
+				if err := c.Get(ctx, client.ObjectKeyFromObject(quota), quota); err != nil {
+					return err
+				}
+				// a concurrent request may have taken the rest of the quota
+				if quotaExceeded := exceedsQuota(quota, quota.Status.Used, increase); len(quotaExceeded) > 0 {
+					exceeded = append(exceeded, quotaExceeded...)
+					return nil
+				}
+			}
+			first = false
+			quota.Status.Used.ReservedIPs += increase.ReservedIPs
+			quota.Status.Used.AssignedReservedIPs += increase.AssignedReservedIPs
+			return c.Status().Update(ctx, quota, fieldOwner)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(exceeded) > 0 {
+			return exceeded, nil
+		}
+	}
+	return nil, nil
+}
+
+// statusQuotaUsage counts the ReservedIPs of the quota that are allocated or
+// assigned according to their status, except those being deleted and the one
+// named skip.
+func statusQuotaUsage(quota *ociv1alpha1.ReservedIPQuotaSpec, reservedIPs []ociv1alpha1.ReservedIP, skip string) ociv1alpha1.ReservedIPQuotaUsage {
+	var used ociv1alpha1.ReservedIPQuotaUsage
+	for i := range reservedIPs {
+		reservedIP := &reservedIPs[i]
+		if reservedIP.Name == skip || !reservedIP.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if counted, _ := quotaCounts(quota, &reservedIP.Spec); !counted {
+			continue
+		}
+		switch reservedIP.Status.State {
+		case "":
+		case "assigning", "assigned", "reassigning", "unassigning":
+			used.ReservedIPs++
+			used.AssignedReservedIPs++
+		default:
+			used.ReservedIPs++
+		}
+	}
+	return used
+}
+
+// enforceQuotas sets the QuotaExceeded condition of a ReservedIP and returns
+// true if allocating it, or assigning it if it is allocated, would exceed a
+// ReservedIPQuota, e.g. because it was created while the admission webhook
+// was down. Only the other ReservedIPs that are already allocated or assigned
+// are counted, so ReservedIPs over quota wait for others to be unassigned or
+// deleted without blocking those within the quota.
+func (r *ReservedIPReconciler) enforceQuotas(ctx context.Context, reservedIP reservedIPObject, log logr.Logger) (bool, error) {
+	status := reservedIP.GetStatus()
+	var increase ociv1alpha1.ReservedIPQuotaUsage
+	switch status.State {
+	case "":
+		increase.ReservedIPs = 1
+	case "allocated":
+		if hasAssignmentTarget(*reservedIP.GetSpec()) {
+			increase.AssignedReservedIPs = 1
+		}
+	}
+
+	var exceeded []string
+	if reservedIP.GetNamespace() != "" && increase != (ociv1alpha1.ReservedIPQuotaUsage{}) {
+		var quotas ociv1alpha1.ReservedIPQuotaList
+		if err := r.List(ctx, &quotas, client.InNamespace(reservedIP.GetNamespace())); err != nil {
+			return false, err
+		}
+		var reservedIPs ociv1alpha1.ReservedIPList
+		if len(quotas.Items) > 0 {
+			if err := r.List(ctx, &reservedIPs, client.InNamespace(reservedIP.GetNamespace())); err != nil {
+				return false, err
+			}
+		}
+		for i := range quotas.Items {
+			quota := &quotas.Items[i]
+			if counted, _ := quotaCounts(&quota.Spec, reservedIP.GetSpec()); !counted {
+				continue
+			}
+			used := statusQuotaUsage(&quota.Spec, reservedIPs.Items, reservedIP.GetName())
+			exceeded = append(exceeded, exceedsQuota(quota, used, increase)...)
+		}
+	}
+
+	if len(exceeded) == 0 {
+		if !meta.IsStatusConditionTrue(status.Conditions, ociv1alpha1.ReservedIPQuotaExceeded) {
+			return false, nil
+		}
+		patch := mergeFrom(reservedIP)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ociv1alpha1.ReservedIPQuotaExceeded,
+			Status:  metav1.ConditionFalse,
+			Reason:  "WithinQuota",
+			Message: "The ReservedIP is within all ReservedIPQuotas",
+		})
+		return false, r.Status().Patch(ctx, reservedIP, patch, fieldOwner)
+	}
+
+	message := strings.Join(exceeded, "; ")
+	condition := meta.FindStatusCondition(status.Conditions, ociv1alpha1.ReservedIPQuotaExceeded)
+	if condition != nil && condition.Status == metav1.ConditionTrue && condition.Message == message {
+		return true, nil
+	}
+	log.Info("ReservedIP exceeds ReservedIPQuotas; not allocating or assigning it", "exceeded", exceeded)
+	patch := mergeFrom(reservedIP)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    ociv1alpha1.ReservedIPQuotaExceeded,
+		Status:  metav1.ConditionTrue,
+		Reason:  "QuotaExceeded",
+		Message: message,
+	})
+	if err := r.Status().Patch(ctx, reservedIP, patch, fieldOwner); err != nil {
+		return true, err
+	}
+	r.Recorder.Event(reservedIP, "Warning", "QuotaExceeded", message)
+	return true, nil
+}
+
+// enqueueOverQuota enqueues the ReservedIPs of the namespace of a changed
+// ReservedIPQuota that wait because they exceed a quota.
+func enqueueOverQuota(c client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		var reservedIPs ociv1alpha1.ReservedIPList
+		if err := c.List(context.Background(), &reservedIPs, client.InNamespace(obj.GetNamespace())); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, reservedIP := range reservedIPs.Items {
+			if meta.IsStatusConditionTrue(reservedIP.Status.Conditions, ociv1alpha1.ReservedIPQuotaExceeded) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: reservedIP.Namespace, Name: reservedIP.Name}})
+			}
+		}
+		return requests
+	})
+}
+
+func (r *ReservedIPQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// status updates are reservations of the ReservedIPValidator, which
+		// must not be recounted before the ReservedIP is stored
+		For(&ociv1alpha1.ReservedIPQuota{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &ociv1alpha1.ReservedIP{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			var quotas ociv1alpha1.ReservedIPQuotaList
+			if err := mgr.GetClient().List(context.Background(), &quotas, client.InNamespace(obj.GetNamespace())); err != nil {
+				return nil
+			}
+			var requests []reconcile.Request
+			for _, quota := range quotas.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: quota.Namespace, Name: quota.Name}})
+			}
+			return requests
+		})).
+		Complete(r)
+}
@@ -0,0 +1,330 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestQuotaCounts(t *testing.T) {
+	pool := ociv1alpha1.ReservedIPQuotaSpec{PublicIPPoolID: "ocid1.publicippool.oc1..a"}
+
+	tests := []struct {
+		name         string
+		quota        ociv1alpha1.ReservedIPQuotaSpec
+		spec         ociv1alpha1.ReservedIPSpec
+		wantCounted  bool
+		wantAssigned bool
+	}{
+		{
+			name:        "unassigned",
+			wantCounted: true,
+		},
+		{
+			name:         "assigned",
+			spec:         ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}},
+			wantCounted:  true,
+			wantAssigned: true,
+		},
+		{
+			name:        "from the pool of the quota",
+			quota:       pool,
+			spec:        ociv1alpha1.ReservedIPSpec{PublicIPPoolID: "ocid1.publicippool.oc1..a"},
+			wantCounted: true,
+		},
+		{
+			name:  "from another pool",
+			quota: pool,
+			spec:  ociv1alpha1.ReservedIPSpec{PublicIPPoolID: "ocid1.publicippool.oc1..b", Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}},
+		},
+		{
+			name:  "from Oracle's pool",
+			quota: pool,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counted, assigned := quotaCounts(&tt.quota, &tt.spec)
+			if counted != tt.wantCounted || assigned != tt.wantAssigned {
+				t.Errorf("quotaCounts() = %v, %v, want %v, %v", counted, assigned, tt.wantCounted, tt.wantAssigned)
+			}
+		})
+	}
+}
+
+func TestQuotaIncrease(t *testing.T) {
+	unassigned := &ociv1alpha1.ReservedIPSpec{}
+	assigned := &ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}}
+	reassigned := &ociv1alpha1.ReservedIPSpec{Assignment: &ociv1alpha1.ReservedIPAssignment{PodName: "pod-b"}}
+	pool := ociv1alpha1.ReservedIPQuotaSpec{PublicIPPoolID: "ocid1.publicippool.oc1..a"}
+	fromPool := &ociv1alpha1.ReservedIPSpec{PublicIPPoolID: "ocid1.publicippool.oc1..a", Assignment: assigned.Assignment}
+
+	tests := []struct {
+		name  string
+		quota ociv1alpha1.ReservedIPQuotaSpec
+		old   *ociv1alpha1.ReservedIPSpec
+		spec  *ociv1alpha1.ReservedIPSpec
+		want  ociv1alpha1.ReservedIPQuotaUsage
+	}{
+		{
+			name: "create unassigned",
+			spec: unassigned,
+			want: ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+		},
+		{
+			name: "create assigned",
+			spec: assigned,
+			want: ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+		},
+		{
+			name: "assign",
+			old:  unassigned,
+			spec: assigned,
+			want: ociv1alpha1.ReservedIPQuotaUsage{AssignedReservedIPs: 1},
+		},
+		{
+			name: "reassign",
+			old:  assigned,
+			spec: reassigned,
+		},
+		{
+			name: "unassign",
+			old:  assigned,
+			spec: unassigned,
+		},
+		{
+			name:  "move into the pool of the quota",
+			quota: pool,
+			old:   assigned,
+			spec:  fromPool,
+			want:  ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+		},
+		{
+			name:  "not counted",
+			quota: pool,
+			spec:  assigned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quotaIncrease(&tt.quota, tt.old, tt.spec); got != tt.want {
+				t.Errorf("quotaIncrease() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceedsQuota(t *testing.T) {
+	quota := &ociv1alpha1.ReservedIPQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "q"},
+		Spec:       ociv1alpha1.ReservedIPQuotaSpec{Hard: ociv1alpha1.ReservedIPQuotaLimits{ReservedIPs: int32Ptr(2), AssignedReservedIPs: int32Ptr(1)}},
+	}
+
+	tests := []struct {
+		name     string
+		quota    *ociv1alpha1.ReservedIPQuota
+		used     ociv1alpha1.ReservedIPQuotaUsage
+		increase ociv1alpha1.ReservedIPQuotaUsage
+		want     []string
+	}{
+		{
+			name:     "within quota",
+			quota:    quota,
+			used:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+		},
+		{
+			name:     "ReservedIPs exceeded",
+			quota:    quota,
+			used:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 2},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+			want:     []string{"ReservedIPQuota q: 2 of 2 ReservedIPs used"},
+		},
+		{
+			name:     "assigned ReservedIPs exceeded",
+			quota:    quota,
+			used:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{AssignedReservedIPs: 1},
+			want:     []string{"ReservedIPQuota q: 1 of 1 assigned ReservedIPs used"},
+		},
+		{
+			name:     "unassigning over quota is allowed",
+			quota:    quota,
+			used:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 5, AssignedReservedIPs: 5},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{},
+		},
+		{
+			name:     "assigning is only checked against the assigned limit",
+			quota:    quota,
+			used:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 5},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{AssignedReservedIPs: 1},
+		},
+		{
+			name:     "unlimited",
+			quota:    &ociv1alpha1.ReservedIPQuota{ObjectMeta: metav1.ObjectMeta{Name: "q"}},
+			used:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 100, AssignedReservedIPs: 100},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+		},
+		{
+			name:     "zero allows nothing",
+			quota:    &ociv1alpha1.ReservedIPQuota{ObjectMeta: metav1.ObjectMeta{Name: "q"}, Spec: ociv1alpha1.ReservedIPQuotaSpec{Hard: ociv1alpha1.ReservedIPQuotaLimits{ReservedIPs: int32Ptr(0)}}},
+			increase: ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+			want:     []string{"ReservedIPQuota q: 0 of 0 ReservedIPs used"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exceedsQuota(tt.quota, tt.used, tt.increase); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exceedsQuota() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckQuotas(t *testing.T) {
+	assignment := &ociv1alpha1.ReservedIPAssignment{PodName: "pod-a"}
+	reservedIP := func(assignment *ociv1alpha1.ReservedIPAssignment) *ociv1alpha1.ReservedIP {
+		return &ociv1alpha1.ReservedIP{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"},
+			Spec:       ociv1alpha1.ReservedIPSpec{Assignment: assignment},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		used         ociv1alpha1.ReservedIPQuotaUsage
+		old          *ociv1alpha1.ReservedIP
+		reservedIP   *ociv1alpha1.ReservedIP
+		dryRun       bool
+		wantExceeded []string
+		wantUsed     ociv1alpha1.ReservedIPQuotaUsage
+	}{
+		{
+			name:       "create reserves the quota",
+			used:       ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+			reservedIP: reservedIP(assignment),
+			wantUsed:   ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 2, AssignedReservedIPs: 1},
+		},
+		{
+			name:       "dry run doesn't reserve",
+			used:       ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+			reservedIP: reservedIP(assignment),
+			dryRun:     true,
+			wantUsed:   ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1},
+		},
+		{
+			name:         "create over quota",
+			used:         ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 2},
+			reservedIP:   reservedIP(nil),
+			wantExceeded: []string{"ReservedIPQuota q: 2 of 2 ReservedIPs used"},
+			wantUsed:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 2},
+		},
+		{
+			name:         "nothing is reserved if one limit is exceeded",
+			used:         ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+			reservedIP:   reservedIP(assignment),
+			wantExceeded: []string{"ReservedIPQuota q: 1 of 1 assigned ReservedIPs used"},
+			wantUsed:     ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 1, AssignedReservedIPs: 1},
+		},
+		{
+			name:       "unassigning over quota is allowed",
+			used:       ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 3, AssignedReservedIPs: 3},
+			old:        reservedIP(assignment),
+			reservedIP: reservedIP(nil),
+			wantUsed:   ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 3, AssignedReservedIPs: 3},
+		},
+		{
+			name:       "reassigning over quota is allowed",
+			used:       ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 3, AssignedReservedIPs: 3},
+			old:        reservedIP(assignment),
+			reservedIP: reservedIP(&ociv1alpha1.ReservedIPAssignment{PodName: "pod-b"}),
+			wantUsed:   ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 3, AssignedReservedIPs: 3},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quota := &ociv1alpha1.ReservedIPQuota{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "q"},
+				Spec:       ociv1alpha1.ReservedIPQuotaSpec{Hard: ociv1alpha1.ReservedIPQuotaLimits{ReservedIPs: int32Ptr(2), AssignedReservedIPs: int32Ptr(1)}},
+				Status:     ociv1alpha1.ReservedIPQuotaStatus{Used: tt.used},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(quota).Build()
+
+			exceeded, err := checkQuotas(context.Background(), c, tt.old, tt.reservedIP, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(exceeded, tt.wantExceeded) {
+				t.Errorf("checkQuotas() = %q, want %q", exceeded, tt.wantExceeded)
+			}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(quota), quota); err != nil {
+				t.Fatal(err)
+			}
+			if quota.Status.Used != tt.wantUsed {
+				t.Errorf("used = %+v, want %+v", quota.Status.Used, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestStatusQuotaUsage(t *testing.T) {
+	withState := func(name, state string) ociv1alpha1.ReservedIP {
+		return ociv1alpha1.ReservedIP{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Status:     ociv1alpha1.ReservedIPStatus{State: state},
+		}
+	}
+	deleting := withState("deleting", "assigned")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	reservedIPs := []ociv1alpha1.ReservedIP{
+		withState("new", ""),
+		withState("allocating", "allocating"),
+		withState("allocated", "allocated"),
+		withState("assigning", "assigning"),
+		withState("assigned", "assigned"),
+		withState("unassigning", "unassigning"),
+		withState("self", "assigned"),
+		deleting,
+	}
+
+	got := statusQuotaUsage(&ociv1alpha1.ReservedIPQuotaSpec{}, reservedIPs, "self")
+	want := ociv1alpha1.ReservedIPQuotaUsage{ReservedIPs: 5, AssignedReservedIPs: 3}
+	if got != want {
+		t.Errorf("statusQuotaUsage() = %+v, want %+v", got, want)
+	}
+}
@@ -1,9 +1,26 @@
 package controllers
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
 const (
 	finalizerName = "oci.k8s.logmein.com"
+
+	leaseField = ".spec.assignment.leaseName"
+
+	assignmentTargetField = ".assignmentTarget"
 )
 
+// fieldOwner is the field manager of all patches made by the operator, so
+// its changes can be told apart from those of users in managedFields.
+const fieldOwner = client.FieldOwner("k8s-oci-operator")
+
 func containsString(slice []string, s string) bool {
 	for _, item := range slice {
 		if item == s {
@@ -13,12 +30,62 @@ func containsString(slice []string, s string) bool {
 	return false
 }
 
-func removeString(slice []string, s string) (result []string) {
-	for _, item := range slice {
-		if item == s {
+// mergeFrom returns a JSON merge patch with the changes made to obj after the
+// call. Only changed fields are sent, so concurrent changes to other fields
+// aren't overwritten.
+func mergeFrom(obj client.Object) client.Patch {
+	return client.MergeFrom(obj.DeepCopyObject().(client.Object))
+}
+
+// lockedMergeFrom is like mergeFrom, but the patch fails with a conflict if
+// obj was changed in the meantime. Used for lists, which a merge patch
+// replaces as a whole, and for changes that must not race.
+func lockedMergeFrom(obj client.Object) client.Patch {
+	return client.MergeFromWithOptions(obj.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+}
+
+// finalizerPatch returns a JSON patch applying ops to the finalizers of obj
+// only if they are still the same. Unlike a merge patch it doesn't drop
+// finalizers added by others in the meantime, and unlike an optimistic lock it
+// doesn't conflict with unrelated changes, e.g. to the status.
+func finalizerPatch(obj client.Object, ops ...map[string]interface{}) (client.Patch, error) {
+	// a test against null passes if the object has no finalizers
+	var finalizers interface{}
+	if len(obj.GetFinalizers()) > 0 {
+		finalizers = obj.GetFinalizers()
+	}
+	ops = append([]map[string]interface{}{{"op": "test", "path": "/metadata/finalizers", "value": finalizers}}, ops...)
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	return client.RawPatch(types.JSONPatchType, data), nil
+}
+
+// addFinalizer adds the operator's finalizer to obj
+func addFinalizer(ctx context.Context, c client.Client, obj client.Object) error {
+	op := map[string]interface{}{"op": "add", "path": "/metadata/finalizers/-", "value": finalizerName}
+	if len(obj.GetFinalizers()) == 0 {
+		op = map[string]interface{}{"op": "add", "path": "/metadata/finalizers", "value": []string{finalizerName}}
+	}
+	patch, err := finalizerPatch(obj, op)
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, obj, patch, fieldOwner)
+}
+
+// removeFinalizer removes the operator's finalizer from obj
+func removeFinalizer(ctx context.Context, c client.Client, obj client.Object) error {
+	for i, finalizer := range obj.GetFinalizers() {
+		if finalizer != finalizerName {
 			continue
 		}
-		result = append(result, item)
+		patch, err := finalizerPatch(obj, map[string]interface{}{"op": "remove", "path": fmt.Sprintf("/metadata/finalizers/%d", i)})
+		if err != nil {
+			return err
+		}
+		return c.Patch(ctx, obj, patch, fieldOwner)
 	}
-	return
+	return nil
 }
@@ -0,0 +1,115 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+)
+
+func TestFinalizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		finalizers []string
+		// finalizers of the object in the API server, if they changed since
+		// it was read
+		stored  []string
+		remove  bool
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "add first",
+			want: []string{finalizerName},
+		},
+		{
+			name:       "add after others",
+			finalizers: []string{"other"},
+			want:       []string{"other", finalizerName},
+		},
+		{
+			name:    "add after a concurrent add",
+			stored:  []string{"other"},
+			want:    []string{"other"},
+			wantErr: true,
+		},
+		{
+			name:       "remove",
+			finalizers: []string{"other", finalizerName, "another"},
+			remove:     true,
+			want:       []string{"other", "another"},
+		},
+		{
+			name:       "remove after a concurrent remove",
+			finalizers: []string{"other", finalizerName},
+			stored:     []string{finalizerName},
+			remove:     true,
+			want:       []string{finalizerName},
+			wantErr:    true,
+		},
+		{
+			name:       "remove missing",
+			finalizers: []string{"other"},
+			remove:     true,
+			want:       []string{"other"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := ociv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.finalizers
+			if tt.stored != nil {
+				stored = tt.stored
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ociv1alpha1.ReservedIP{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", Finalizers: stored},
+			}).Build()
+			reservedIP := &ociv1alpha1.ReservedIP{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", Finalizers: tt.finalizers},
+			}
+
+			var err error
+			if tt.remove {
+				err = removeFinalizer(context.Background(), c, reservedIP)
+			} else {
+				err = addFinalizer(context.Background(), c, reservedIP)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got ociv1alpha1.ReservedIP
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(reservedIP), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Finalizers, tt.want) {
+				t.Errorf("finalizers = %v, want %v", got.Finalizers, tt.want)
+			}
+		})
+	}
+}
@@ -16,23 +16,27 @@ limitations under the License.
 package main
 
 import (
-	"errors"
+	"context"
 	"flag"
 	"os"
 
 	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
 	"github.com/logmein/k8s-oci-operator/controllers"
+	"github.com/logmein/k8s-oci-operator/pkg/config"
+	"github.com/logmein/k8s-oci-operator/pkg/oci"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
-
 	// +kubebuilder:scaffold:imports
-
-	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
-	ociauth "github.com/oracle/oci-go-sdk/v31/common/auth"
-	ocicore "github.com/oracle/oci-go-sdk/v31/core"
 )
 
 var (
@@ -42,21 +46,19 @@ var (
 
 func init() {
 	corev1.AddToScheme(scheme)
+	authorizationv1.AddToScheme(scheme)
+	coordinationv1.AddToScheme(scheme)
 	ociv1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
-	var metricsAddr, leaderElectionID, leaderElectionNamespace, compartmentID, vcnID, reservedIPNamePrefix, ociConfigFile string
+	var cfg config.OperatorConfig
+	var configFile string
 	var ipr bool
-	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&leaderElectionID, "leader-election-id", "k8s-oci-operator", "the name of the configmap do use as leader election lock")
-	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "the namespace in which the leader election lock will be held")
-	flag.StringVar(&compartmentID, "compartment-id", "", "OCI compartment ID")
-	flag.StringVar(&vcnID, "vcn-id", "", "OCI Virtual Cloud Network (VCN) ID")
-	flag.StringVar(&reservedIPNamePrefix, "reserved-ip-name-prefix", "", "Name prefix to add to all ReservedIPs created by this controller")
-	flag.BoolVar(&ipr, "instance-principals", false, "Use instance principals to talk to OCI API")
-	flag.StringVar(&ociConfigFile, "oci-config", "", "OCI config file to use")
+	flag.StringVar(&configFile, "config", "", "Operator configuration file; flags override its values")
+	cfg.BindFlags(flag.CommandLine)
+	flag.BoolVar(&ipr, "instance-principals", false, "Use instance principals to talk to OCI API (deprecated, use -auth=instance-principal)")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,72 +67,248 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	if leaderElectionNamespace == "" {
-		setupLog.Error(errors.New("-leader-election-namespace flag is required"), "command line flag validation failed")
+	if configFile != "" {
+		if err := cfg.Load(configFile, flag.CommandLine); err != nil {
+			setupLog.Error(err, "unable to load config file")
+			os.Exit(1)
+		}
+	}
+	if ipr {
+		cfg.OCI.Auth = oci.AuthInstancePrincipal
+	}
+	if err := cfg.Validate(); err != nil {
+		setupLog.Error(err, "configuration validation failed")
 		os.Exit(1)
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                  scheme,
-		MetricsBindAddress:      metricsAddr,
+		MetricsBindAddress:      cfg.MetricsAddr,
 		LeaderElection:          true,
-		LeaderElectionNamespace: leaderElectionNamespace,
-		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: cfg.LeaderElection.Namespace,
+		LeaderElectionID:        cfg.LeaderElection.ID,
+		SyncPeriod:              &cfg.SyncPeriod.Duration,
+		Controller: ctrlconfig.ControllerConfigurationSpec{
+			GroupKindConcurrency: cfg.GroupKindConcurrency(),
+		},
+		// the node heartbeats in kube-node-lease are never followed by
+		// ReservedIPs, don't cache them
+		NewCache: cache.BuilderWithOptions(cache.Options{
+			SelectorsByObject: cache.SelectorsByObject{
+				&coordinationv1.Lease{}: {Field: fields.OneTermNotEqualSelector("metadata.namespace", corev1.NamespaceNodeLease)},
+			},
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	var ocicfg ocicommon.ConfigurationProvider
-	if ipr {
-		ocicfg, err = ociauth.InstancePrincipalConfigurationProvider()
+	if cfg.DryRun {
+		setupLog.Info("dry-run mode: OCI public IPs won't be created, updated or deleted")
+	}
+
+	ocicfg, err := oci.NewConfigurationProvider(cfg.OCI.Auth, cfg.OCI.ConfigFile)
+	if err != nil {
+		setupLog.Error(err, "unable to create OCI config", "authMode", cfg.OCI.Auth)
+		os.Exit(1)
+	}
+	setupLog.Info("using OCI principal", oci.DescribePrincipal(cfg.OCI.Auth, ocicfg)...)
+
+	vnc, err := oci.NewVirtualNetworkClient(ocicfg)
+	if err != nil {
+		setupLog.Error(err, "unable to create OCI client")
+		os.Exit(1)
+	}
+	vnc.UserAgent = "k8s-oci-operator"
+	if err := cfg.ClientOptions().Configure(&vnc, true); err != nil {
+		setupLog.Error(err, "unable to configure OCI client")
+		os.Exit(1)
+	}
+	if cfg.OCI.Endpoint != "" {
+		setupLog.Info("using custom OCI endpoint", "endpoint", cfg.OCI.Endpoint)
+	}
+
+	metadata := &oci.Metadata{BaseURL: cfg.OCI.MetadataURL}
+	if cfg.CompartmentID == "" {
+		cfg.CompartmentID, err = metadata.CompartmentID(context.Background())
 		if err != nil {
-			setupLog.Error(err, "unable to create OCI config via instance principals")
+			setupLog.Error(err, "-compartment-id not given and unable to discover it from the instance metadata service", "metadataURL", cfg.OCI.MetadataURL)
 			os.Exit(1)
 		}
-		setupLog.Info("using OCI instance principle client")
-	} else if ociConfigFile != "" {
-		ocicfg, err = ocicommon.ConfigurationProviderFromFile(ociConfigFile, "")
+		setupLog.Info("discovered compartment from instance metadata", "compartmentID", cfg.CompartmentID)
+	}
+	if cfg.VcnID == "" {
+		cfg.VcnID, err = metadata.VcnID(context.Background(), &vnc)
 		if err != nil {
-			setupLog.Error(err, "unable to create OCI config from config file")
+			setupLog.Error(err, "-vcn-id not given and unable to discover it from the instance metadata service", "metadataURL", cfg.OCI.MetadataURL)
 			os.Exit(1)
 		}
-		setupLog.Info("using OCI configuration file", ociConfigFile)
-
-	} else {
-		ocicfg = ocicommon.DefaultConfigProvider()
-		setupLog.Info("Using OCI default client")
+		setupLog.Info("discovered VCN from instance metadata", "vcnID", cfg.VcnID)
 	}
 
-	vnc, err := ocicore.NewVirtualNetworkClientWithConfigurationProvider(ocicfg)
-	if err != nil {
-		setupLog.Error(err, "unable to create OCI client")
+	apiCheck := &oci.APICheck{
+		VNC:      &vnc,
+		VcnID:    cfg.VcnID,
+		Interval: cfg.OCI.APICheckInterval.Duration,
+		Log:      ctrl.Log.WithName("oci-api-check"),
+	}
+	if err := apiCheck.Check(context.Background()); err != nil {
+		setupLog.Error(err, "unable to call OCI VirtualNetwork API; check that the principal may read the VCN", append(oci.DescribePrincipal(cfg.OCI.Auth, ocicfg), "vcnID", cfg.VcnID)...)
 		os.Exit(1)
 	}
-	vnc.UserAgent = "k8s-oci-operator"
+	if cfg.OCI.APICheckInterval.Duration > 0 {
+		if err := mgr.Add(apiCheck); err != nil {
+			setupLog.Error(err, "unable to add OCI API check")
+			os.Exit(1)
+		}
+	}
+	if cfg.ClusterID == "" {
+		var ns corev1.Namespace
+		if err := mgr.GetAPIReader().Get(context.Background(), client.ObjectKey{Name: metav1.NamespaceSystem}, &ns); err != nil {
+			setupLog.Error(err, "-cluster-id not given and unable to read the UID of the kube-system namespace")
+			os.Exit(1)
+		}
+		cfg.ClusterID = string(ns.UID)
+		setupLog.Info("using the UID of the kube-system namespace as cluster ID", "clusterID", cfg.ClusterID)
+	}
+	networkCache := &controllers.NetworkCache{SubnetRefreshInterval: cfg.SubnetCacheRefreshInterval.Duration}
+	var accounts *controllers.OCIAccountClients
+	if cfg.Features.OCIAccounts {
+		// only the secrets of the namespace for OCIAccounts are cached
+		secrets, err := cache.New(mgr.GetConfig(), cache.Options{
+			Scheme:    mgr.GetScheme(),
+			Mapper:    mgr.GetRESTMapper(),
+			Namespace: cfg.AccountSecretNamespace(),
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create cache for OCIAccount secrets")
+			os.Exit(1)
+		}
+		if err := mgr.Add(secrets); err != nil {
+			setupLog.Error(err, "unable to add cache for OCIAccount secrets")
+			os.Exit(1)
+		}
+		accounts = &controllers.OCIAccountClients{
+			Secrets:                    secrets,
+			SecretNamespace:            cfg.AccountSecretNamespace(),
+			SubnetCacheRefreshInterval: cfg.SubnetCacheRefreshInterval.Duration,
+			ClientOptions:              cfg.ClientOptions(),
+		}
+	}
 
 	err = (&controllers.ReservedIPReconciler{
 		Client:               mgr.GetClient(),
 		Recorder:             mgr.GetEventRecorderFor("k8s-oci-operator"),
 		Log:                  ctrl.Log.WithName("controllers").WithName("ReservedIP"),
-		CompartmentID:        compartmentID,
-		VcnID:                vcnID,
-		ReservedIPNamePrefix: reservedIPNamePrefix,
+		CompartmentID:        cfg.CompartmentID,
+		VcnID:                cfg.VcnID,
+		ReservedIPNamePrefix: cfg.ReservedIPNamePrefix,
+		ClusterID:            cfg.ClusterID,
 		VNC:                  &vnc,
+		NetworkCache:         networkCache,
+		Accounts:             accounts,
+		DryRun:               cfg.DryRun,
+		EnforcePolicies:      cfg.Features.ReservedIPPolicies,
+		EnforceQuotas:        cfg.Features.ReservedIPQuotas,
+		AllowForceRelease:    cfg.Features.ForceRelease,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ReservedIP")
 		os.Exit(1)
 	}
-	err = (&controllers.ReservedIPAssociationReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("ReservedIPAssociation"),
+	if cfg.Features.AdmissionWebhook {
+		err = (&controllers.ReservedIPValidator{
+			Client: mgr.GetClient(),
+		}).SetupWebhookWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ReservedIP")
+			os.Exit(1)
+		}
+		err = (&controllers.ForceAnnotationValidator{
+			Client: mgr.GetClient(),
+		}).SetupWebhookWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ForceAnnotations")
+			os.Exit(1)
+		}
+	}
+	err = (&controllers.ClusterReservedIPReconciler{
+		ReservedIPReconciler: controllers.ReservedIPReconciler{
+			Client:               mgr.GetClient(),
+			Recorder:             mgr.GetEventRecorderFor("k8s-oci-operator"),
+			Log:                  ctrl.Log.WithName("controllers").WithName("ClusterReservedIP"),
+			CompartmentID:        cfg.CompartmentID,
+			VcnID:                cfg.VcnID,
+			ReservedIPNamePrefix: cfg.ReservedIPNamePrefix,
+			ClusterID:            cfg.ClusterID,
+			VNC:                  &vnc,
+			NetworkCache:         networkCache,
+			Accounts:             accounts,
+			DryRun:               cfg.DryRun,
+			AllowForceRelease:    cfg.Features.ForceRelease,
+		},
 	}).SetupWithManager(mgr)
 	if err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ReservedIPAssociation")
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterReservedIP")
 		os.Exit(1)
 	}
+	if cfg.Features.ReservedIPAssociations {
+		err = (&controllers.ReservedIPAssociationReconciler{
+			Client:            mgr.GetClient(),
+			Recorder:          mgr.GetEventRecorderFor("k8s-oci-operator"),
+			Log:               ctrl.Log.WithName("controllers").WithName("ReservedIPAssociation"),
+			AllowForceAbandon: cfg.Features.ForceRelease,
+		}).SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ReservedIPAssociation")
+			os.Exit(1)
+		}
+	}
+	if cfg.Features.ReservedIPClaims {
+		err = (&controllers.ReservedIPClaimReconciler{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("k8s-oci-operator"),
+			Log:      ctrl.Log.WithName("controllers").WithName("ReservedIPClaim"),
+		}).SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ReservedIPClaim")
+			os.Exit(1)
+		}
+	}
+	if cfg.Features.ReservedIPFailovers {
+		err = (&controllers.ReservedIPFailoverReconciler{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("k8s-oci-operator"),
+			Log:      ctrl.Log.WithName("controllers").WithName("ReservedIPFailover"),
+		}).SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ReservedIPFailover")
+			os.Exit(1)
+		}
+	}
+	if cfg.Features.ReservedIPQuotas {
+		err = (&controllers.ReservedIPQuotaReconciler{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("controllers").WithName("ReservedIPQuota"),
+		}).SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ReservedIPQuota")
+			os.Exit(1)
+		}
+	}
+	if cfg.Features.NodeEvacuation {
+		err = (&controllers.NodeEvacuationReconciler{
+			Client:        mgr.GetClient(),
+			Recorder:      mgr.GetEventRecorderFor("k8s-oci-operator"),
+			Log:           ctrl.Log.WithName("controllers").WithName("NodeEvacuation"),
+			DefaultPolicy: cfg.NodeEvacuationPolicy,
+		}).SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "NodeEvacuation")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -0,0 +1,293 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config contains the operator's configuration file format.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	ociv1alpha1 "github.com/logmein/k8s-oci-operator/api/v1alpha1"
+	"github.com/logmein/k8s-oci-operator/pkg/oci"
+)
+
+const (
+	// APIVersion is the version of the configuration file format
+	APIVersion = "config.oci.k8s.logmein.com/v1alpha1"
+	// Kind of the configuration file
+	Kind = "OperatorConfig"
+)
+
+// OperatorConfig is the configuration of the operator. It can be loaded from
+// a file, and each value can be overridden by a command line flag.
+type OperatorConfig struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// Address the metric endpoint binds to
+	MetricsAddr string `json:"metricsAddr,omitempty"`
+
+	LeaderElection LeaderElection `json:"leaderElection,omitempty"`
+
+	OCI OCI `json:"oci,omitempty"`
+
+	// OCI compartment to create ReservedIPs in. Discovered from the instance
+	// metadata service if empty.
+	CompartmentID string `json:"compartmentID,omitempty"`
+
+	// OCI VCN the pods' private IPs are in. Discovered from the instance
+	// metadata service if empty.
+	VcnID string `json:"vcnID,omitempty"`
+
+	// Name prefix to add to all ReservedIPs created by the operator
+	ReservedIPNamePrefix string `json:"reservedIPNamePrefix,omitempty"`
+
+	// Identifies the cluster in the freeform tags of the public IPs the
+	// operator allocates. Defaults to the UID of the kube-system namespace.
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// How often all objects are reconciled even if they didn't change
+	SyncPeriod metav1.Duration `json:"syncPeriod,omitempty"`
+
+	// How long to cache the subnets of the VCN
+	SubnetCacheRefreshInterval metav1.Duration `json:"subnetCacheRefreshInterval,omitempty"`
+
+	// Default evacuation policy of ReservedIPs without spec.evacuation
+	NodeEvacuationPolicy ociv1alpha1.EvacuationPolicy `json:"nodeEvacuationPolicy,omitempty"`
+
+	// Only record the OCI calls that would be made, as log messages and
+	// events, instead of making them
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Namespace the secrets of OCIAccounts must be in. Defaults to the
+	// leader election namespace.
+	OCIAccountSecretNamespace string `json:"ociAccountSecretNamespace,omitempty"`
+
+	// Maximum number of concurrent reconciles per kind, e.g. ReservedIP: 4.
+	// Kinds not listed are reconciled one at a time.
+	Concurrency map[string]int `json:"concurrency,omitempty"`
+
+	Features Features `json:"features,omitempty"`
+
+	// names of the flags registered by BindFlags
+	flagNames map[string]bool
+}
+
+// LeaderElection configures the leader election lock
+type LeaderElection struct {
+	ID        string `json:"id,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// OCI configures how the operator talks to the OCI API
+type OCI struct {
+	Auth       oci.AuthMode `json:"auth,omitempty"`
+	ConfigFile string       `json:"configFile,omitempty"`
+
+	Endpoint string          `json:"endpoint,omitempty"`
+	Proxy    string          `json:"proxy,omitempty"`
+	CABundle string          `json:"caBundle,omitempty"`
+	Timeout  metav1.Duration `json:"timeout,omitempty"`
+
+	MetadataURL      string          `json:"metadataURL,omitempty"`
+	APICheckInterval metav1.Duration `json:"apiCheckInterval,omitempty"`
+}
+
+// Features enables or disables the optional controllers
+type Features struct {
+	ReservedIPAssociations bool `json:"reservedIPAssociations"`
+	ReservedIPClaims       bool `json:"reservedIPClaims"`
+	ReservedIPFailovers    bool `json:"reservedIPFailovers"`
+	NodeEvacuation         bool `json:"nodeEvacuation"`
+	OCIAccounts            bool `json:"ociAccounts"`
+	ReservedIPPolicies     bool `json:"reservedIPPolicies"`
+	ReservedIPQuotas       bool `json:"reservedIPQuotas"`
+	AdmissionWebhook       bool `json:"admissionWebhook"`
+	ForceRelease           bool `json:"forceRelease"`
+}
+
+// BindFlags registers a flag for each value of the configuration, with the
+// default value of the configuration.
+func (c *OperatorConfig) BindFlags(fs *flag.FlagSet) {
+	existing := map[string]bool{}
+	fs.VisitAll(func(f *flag.Flag) {
+		existing[f.Name] = true
+	})
+	defer func() {
+		c.flagNames = map[string]bool{}
+		fs.VisitAll(func(f *flag.Flag) {
+			if !existing[f.Name] {
+				c.flagNames[f.Name] = true
+			}
+		})
+	}()
+
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	fs.StringVar(&c.LeaderElection.ID, "leader-election-id", "k8s-oci-operator", "the name of the configmap do use as leader election lock")
+	fs.StringVar(&c.LeaderElection.Namespace, "leader-election-namespace", "", "the namespace in which the leader election lock will be held")
+	fs.StringVar(&c.CompartmentID, "compartment-id", "", "OCI compartment ID (defaults to the compartment of the node)")
+	fs.StringVar(&c.VcnID, "vcn-id", "", "OCI Virtual Cloud Network (VCN) ID (defaults to the VCN of the node)")
+	fs.StringVar(&c.OCI.MetadataURL, "metadata-url", oci.DefaultMetadataURL, "Base URL of the OCI instance metadata service used to discover the compartment and VCN")
+	fs.StringVar(&c.ReservedIPNamePrefix, "reserved-ip-name-prefix", "", "Name prefix to add to all ReservedIPs created by this controller")
+	fs.StringVar(&c.ClusterID, "cluster-id", "", "Identifier of the cluster to tag allocated public IPs with (defaults to the UID of the kube-system namespace)")
+	fs.StringVar((*string)(&c.OCI.Auth), "auth", string(oci.AuthUser), "How to authenticate to the OCI API: user (OCI config file), instance-principal, resource-principal or workload-identity")
+	fs.StringVar(&c.OCI.ConfigFile, "oci-config", "", "OCI config file to use with -auth=user")
+	fs.StringVar(&c.OCI.Endpoint, "oci-endpoint", "", "Custom OCI VirtualNetwork API endpoint, e.g. https://localhost:8443 for a local simulator")
+	fs.StringVar(&c.OCI.Proxy, "oci-proxy", "", "HTTP(S) proxy for the OCI API (defaults to the HTTPS_PROXY environment variable)")
+	fs.StringVar(&c.OCI.CABundle, "oci-ca-bundle", "", "PEM file with additional CAs to trust for the OCI API")
+	fs.DurationVar(&c.OCI.Timeout.Duration, "oci-timeout", 60*time.Second, "Timeout of requests to the OCI API")
+	fs.DurationVar(&c.OCI.APICheckInterval.Duration, "oci-api-check-interval", 5*time.Minute, "How often to check that the OCI credentials can still call the VirtualNetwork API (0 to disable)")
+	fs.DurationVar(&c.SyncPeriod.Duration, "sync-period", 10*time.Hour, "How often all objects are reconciled even if they didn't change")
+	fs.DurationVar(&c.SubnetCacheRefreshInterval.Duration, "subnet-cache-refresh-interval", 5*time.Minute, "How long to cache the subnets of the VCN")
+	fs.StringVar((*string)(&c.NodeEvacuationPolicy), "node-evacuation-policy", string(ociv1alpha1.EvacuationWait), "What to do with ReservedIPs assigned to pods on NotReady or cordoned nodes by default: Wait, Unassign or MoveToStandby")
+	fs.StringVar(&c.OCIAccountSecretNamespace, "oci-account-secret-namespace", "", "Namespace the secrets of OCIAccounts must be in (defaults to the leader election namespace)")
+	fs.BoolVar(&c.DryRun, "dry-run", false, "Only log and record events for the OCI calls that would create, update or delete public IPs instead of making them")
+	fs.BoolVar(&c.Features.ReservedIPAssociations, "enable-reservedip-associations", true, "Run the ReservedIPAssociation controller")
+	fs.BoolVar(&c.Features.ReservedIPClaims, "enable-reservedip-claims", true, "Run the ReservedIPClaim controller")
+	fs.BoolVar(&c.Features.ReservedIPFailovers, "enable-reservedip-failovers", true, "Run the ReservedIPFailover controller")
+	fs.BoolVar(&c.Features.NodeEvacuation, "enable-node-evacuation", true, "Evacuate ReservedIPs from NotReady and cordoned nodes")
+	fs.BoolVar(&c.Features.OCIAccounts, "enable-oci-accounts", true, "Allow ReservedIPs to reference OCIAccounts")
+	fs.BoolVar(&c.Features.ReservedIPPolicies, "enable-reservedip-policies", true, "Enforce ReservedIPPolicies when reconciling ReservedIPs")
+	fs.BoolVar(&c.Features.ReservedIPQuotas, "enable-reservedip-quotas", true, "Run the ReservedIPQuota controller updating the usage of quotas")
+	fs.BoolVar(&c.Features.AdmissionWebhook, "enable-admission-webhook", false, "Serve the validating webhook rejecting ReservedIPs that violate ReservedIPPolicies or exceed ReservedIPQuotas (needs a serving certificate)")
+	fs.BoolVar(&c.Features.ForceRelease, "enable-force-release", false, "Honor the force-release and force-abandon annotations, which leave public IPs behind in OCI (needs -enable-admission-webhook)")
+}
+
+// Load reads the configuration file at path into c. Flags of fs that were set
+// on the command line take precedence over the file.
+func (c *OperatorConfig) Load(path string, fs *flag.FlagSet) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	setFlags := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		if c.flagNames[f.Name] {
+			setFlags[f.Name] = f.Value.String()
+		}
+	})
+
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		return fmt.Errorf("config file %s must have apiVersion %s and kind %s", path, APIVersion, Kind)
+	}
+
+	for name, value := range setFlags {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate returns an error describing the first invalid value
+func (c *OperatorConfig) Validate() error {
+	if c.LeaderElection.Namespace == "" {
+		return errors.New("leaderElection.namespace (-leader-election-namespace) is required")
+	}
+
+	validAuth := false
+	for _, mode := range oci.AuthModes {
+		if c.OCI.Auth == mode {
+			validAuth = true
+		}
+	}
+	if !validAuth {
+		return fmt.Errorf("oci.auth (-auth) must be one of %v", oci.AuthModes)
+	}
+
+	switch c.NodeEvacuationPolicy {
+	case ociv1alpha1.EvacuationWait, ociv1alpha1.EvacuationUnassign, ociv1alpha1.EvacuationMoveToStandby:
+	default:
+		return errors.New("nodeEvacuationPolicy (-node-evacuation-policy) must be one of Wait, Unassign or MoveToStandby")
+	}
+
+	if c.Features.ForceRelease && !c.Features.AdmissionWebhook {
+		// only the webhook knows who sets the force annotations
+		return errors.New("features.forceRelease (-enable-force-release) needs features.admissionWebhook (-enable-admission-webhook), which checks who may set the force annotations")
+	}
+
+	durations := map[string]time.Duration{
+		"oci.timeout (-oci-timeout)":                                  c.OCI.Timeout.Duration,
+		"oci.apiCheckInterval (-oci-api-check-interval)":              c.OCI.APICheckInterval.Duration,
+		"syncPeriod (-sync-period)":                                   c.SyncPeriod.Duration,
+		"subnetCacheRefreshInterval (-subnet-cache-refresh-interval)": c.SubnetCacheRefreshInterval.Duration,
+	}
+	for name, d := range durations {
+		if d < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	for kind, n := range c.Concurrency {
+		if _, ok := concurrencyKinds[kind]; !ok {
+			return fmt.Errorf("concurrency: unknown kind %s", kind)
+		}
+		if n < 1 {
+			return fmt.Errorf("concurrency.%s must be at least 1", kind)
+		}
+	}
+	return nil
+}
+
+// group/kind each controller is registered for
+var concurrencyKinds = map[string]string{
+	"ReservedIP":            "ReservedIP." + ociv1alpha1.GroupVersion.Group,
+	"ClusterReservedIP":     "ClusterReservedIP." + ociv1alpha1.GroupVersion.Group,
+	"ReservedIPAssociation": "ReservedIPAssociation." + ociv1alpha1.GroupVersion.Group,
+	"ReservedIPClaim":       "ReservedIPClaim." + ociv1alpha1.GroupVersion.Group,
+	"ReservedIPFailover":    "ReservedIPFailover." + ociv1alpha1.GroupVersion.Group,
+	"ReservedIPQuota":       "ReservedIPQuota." + ociv1alpha1.GroupVersion.Group,
+	"Node":                  "Node",
+}
+
+// GroupKindConcurrency returns the concurrency per group/kind as expected by
+// the controller manager.
+func (c *OperatorConfig) GroupKindConcurrency() map[string]int {
+	concurrency := map[string]int{}
+	for kind, n := range c.Concurrency {
+		concurrency[concurrencyKinds[kind]] = n
+	}
+	return concurrency
+}
+
+// AccountSecretNamespace returns the namespace the secrets of OCIAccounts must
+// be in
+func (c *OperatorConfig) AccountSecretNamespace() string {
+	if c.OCIAccountSecretNamespace != "" {
+		return c.OCIAccountSecretNamespace
+	}
+	return c.LeaderElection.Namespace
+}
+
+// ClientOptions returns the options for the OCI client
+func (c *OperatorConfig) ClientOptions() oci.ClientOptions {
+	return oci.ClientOptions{
+		Endpoint: c.OCI.Endpoint,
+		ProxyURL: c.OCI.Proxy,
+		CABundle: c.OCI.CABundle,
+		Timeout:  c.OCI.Timeout.Duration,
+	}
+}
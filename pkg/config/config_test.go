@@ -0,0 +1,179 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/logmein/k8s-oci-operator/pkg/oci"
+)
+
+const header = "apiVersion: " + APIVersion + "\nkind: " + Kind + "\n"
+
+// load binds the flags, parses args and loads file if it isn't empty, like
+// main does.
+func load(t *testing.T, file string, args ...string) (*OperatorConfig, error) {
+	t.Helper()
+	var cfg OperatorConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if file == "" {
+		return &cfg, nil
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &cfg, cfg.Load(path, fs)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		args    []string
+		check   func(*OperatorConfig) bool
+		wantErr bool
+	}{
+		{
+			name:  "flag defaults without file",
+			check: func(c *OperatorConfig) bool { return c.SyncPeriod.Duration == 10*time.Hour && c.OCI.Auth == oci.AuthUser },
+		},
+		{
+			name:  "file overrides flag defaults",
+			file:  header + "syncPeriod: 1h\noci:\n  auth: workload-identity\n",
+			check: func(c *OperatorConfig) bool { return c.SyncPeriod.Duration == time.Hour && c.OCI.Auth == oci.AuthWorkloadIdentity },
+		},
+		{
+			name:  "flags override file",
+			file:  header + "syncPeriod: 1h\noci:\n  auth: workload-identity\n",
+			args:  []string{"-sync-period=2h", "-auth=instance-principal"},
+			check: func(c *OperatorConfig) bool { return c.SyncPeriod.Duration == 2*time.Hour && c.OCI.Auth == oci.AuthInstancePrincipal },
+		},
+		{
+			name:  "flags set to their default override file",
+			file:  header + "dryRun: true\n",
+			args:  []string{"-dry-run=false"},
+			check: func(c *OperatorConfig) bool { return !c.DryRun },
+		},
+		{
+			name:  "values missing in file keep flag values",
+			file:  header + "reservedIPNamePrefix: file-\n",
+			args:  []string{"-leader-election-namespace=kube-system"},
+			check: func(c *OperatorConfig) bool { return c.ReservedIPNamePrefix == "file-" && c.LeaderElection.Namespace == "kube-system" },
+		},
+		{
+			name:  "features missing in file keep their defaults",
+			file:  header + "features:\n  admissionWebhook: true\n",
+			check: func(c *OperatorConfig) bool { return c.Features.AdmissionWebhook && c.Features.OCIAccounts },
+		},
+		{
+			name:  "flag overrides feature of file",
+			file:  header + "features:\n  ociAccounts: false\n",
+			args:  []string{"-enable-oci-accounts"},
+			check: func(c *OperatorConfig) bool { return c.Features.OCIAccounts },
+		},
+		{
+			name:    "unknown field",
+			file:    header + "unknown: true\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong kind",
+			file:    "apiVersion: " + APIVersion + "\nkind: Other\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, tt.file, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(cfg) {
+				t.Errorf("unexpected configuration %+v", cfg)
+			}
+		})
+	}
+}
+
+// TestSample makes sure the sample file is valid and documents the defaults
+// of the features.
+func TestSample(t *testing.T) {
+	defaults, err := load(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg OperatorConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.BindFlags(fs)
+	if err := cfg.Load(filepath.Join("..", "..", "config", "samples", "operator_config.yaml"), fs); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Features != defaults.Features {
+		t.Errorf("features of the sample %+v differ from the flag defaults %+v", cfg.Features, defaults.Features)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name: "force release with the admission webhook",
+			args: []string{"-enable-force-release", "-enable-admission-webhook"},
+		},
+		{
+			name:    "force release without the admission webhook",
+			args:    []string{"-enable-force-release"},
+			wantErr: true,
+		},
+		{
+			name:    "negative duration",
+			args:    []string{"-sync-period=-1h"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, "", append([]string{"-leader-election-namespace=kube-system"}, tt.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
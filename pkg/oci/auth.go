@@ -0,0 +1,101 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oci builds the clients the operator uses to talk to the OCI API.
+package oci
+
+import (
+	"fmt"
+
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+	ociauth "github.com/oracle/oci-go-sdk/v31/common/auth"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+)
+
+// AuthMode selects how the operator authenticates to the OCI API
+type AuthMode string
+
+const (
+	// AuthUser uses an API signing key from the given OCI config file or the
+	// SDK's default locations.
+	AuthUser AuthMode = "user"
+	// AuthInstancePrincipal uses the instance principal of the node.
+	AuthInstancePrincipal AuthMode = "instance-principal"
+	// AuthResourcePrincipal uses the resource principal configured in the
+	// OCI_RESOURCE_PRINCIPAL_* environment variables.
+	AuthResourcePrincipal AuthMode = "resource-principal"
+	// AuthWorkloadIdentity uses OKE workload identity, i.e. the principal of
+	// the operator's service account.
+	AuthWorkloadIdentity AuthMode = "workload-identity"
+)
+
+// AuthModes lists all valid auth modes
+var AuthModes = []AuthMode{AuthUser, AuthInstancePrincipal, AuthResourcePrincipal, AuthWorkloadIdentity}
+
+// NewConfigurationProvider returns the configuration provider for mode.
+// configFile is only used with AuthUser; if empty, the SDK's default
+// provider is used.
+func NewConfigurationProvider(mode AuthMode, configFile string) (ocicommon.ConfigurationProvider, error) {
+	switch mode {
+	case AuthUser:
+		if configFile == "" {
+			return ocicommon.DefaultConfigProvider(), nil
+		}
+		return ocicommon.ConfigurationProviderFromFile(configFile, "")
+	case AuthInstancePrincipal:
+		return ociauth.InstancePrincipalConfigurationProvider()
+	case AuthResourcePrincipal:
+		return ociauth.ResourcePrincipalConfigurationProvider()
+	case AuthWorkloadIdentity:
+		return NewWorkloadIdentityConfigurationProvider()
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", mode)
+	}
+}
+
+// NewVirtualNetworkClient returns a VirtualNetwork client for cfg. Providers
+// that sign requests themselves, like workload identity, replace the SDK's
+// signer.
+func NewVirtualNetworkClient(cfg ocicommon.ConfigurationProvider) (ocicore.VirtualNetworkClient, error) {
+	vnc, err := ocicore.NewVirtualNetworkClientWithConfigurationProvider(cfg)
+	if err != nil {
+		return vnc, err
+	}
+	if signer, ok := cfg.(ocicommon.HTTPRequestSigner); ok {
+		vnc.Signer = signer
+	}
+	return vnc, nil
+}
+
+// DescribePrincipal returns key/value pairs describing the principal cfg
+// authenticates as, for logging.
+func DescribePrincipal(mode AuthMode, cfg ocicommon.ConfigurationProvider) []interface{} {
+	keysAndValues := []interface{}{"authMode", mode}
+	add := func(key string, get func() (string, error)) {
+		value, err := get()
+		if err != nil {
+			value = fmt.Sprintf("unknown (%s)", err)
+		}
+		keysAndValues = append(keysAndValues, key, value)
+	}
+
+	add("tenancy", cfg.TenancyOCID)
+	add("region", cfg.Region)
+	if mode == AuthUser {
+		add("user", cfg.UserOCID)
+		add("fingerprint", cfg.KeyFingerprint)
+	}
+	return keysAndValues
+}
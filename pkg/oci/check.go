@@ -0,0 +1,83 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	apiAvailable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "k8s_oci_operator_oci_api_available",
+		Help: "Whether the last check calling the OCI VirtualNetwork API with the operator's credentials succeeded (1) or not (0).",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(apiAvailable)
+}
+
+// APICheck periodically checks that the operator's credentials can still call
+// the VirtualNetwork API, e.g. because a token expired or a policy changed.
+type APICheck struct {
+	VNC      *ocicore.VirtualNetworkClient
+	VcnID    string
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+// Check reads the operator's VCN once.
+func (c *APICheck) Check(ctx context.Context) error {
+	_, err := c.VNC.GetVcn(ctx, ocicore.GetVcnRequest{
+		VcnId: ocicommon.String(c.VcnID),
+	})
+	if err != nil {
+		apiAvailable.Set(0)
+		return err
+	}
+	apiAvailable.Set(1)
+	return nil
+}
+
+// Start runs the check every interval until ctx is done.
+func (c *APICheck) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.Check(ctx); err != nil {
+				c.Log.Error(err, "cannot call OCI VirtualNetwork API; check the operator's credentials and policies", "vcnID", c.VcnID)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, so standby replicas check their
+// credentials, too.
+func (c *APICheck) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+)
+
+// DefaultMetadataURL is the base URL of the OCI instance metadata service (v2)
+const DefaultMetadataURL = "http://169.254.169.254/opc/v2"
+
+// Metadata reads the instance metadata service of the node the operator runs
+// on.
+type Metadata struct {
+	// BaseURL of the metadata service, e.g. DefaultMetadataURL or a local
+	// stand-in for tests.
+	BaseURL string
+
+	HTTPClient *http.Client
+}
+
+type instanceMetadata struct {
+	CompartmentID string `json:"compartmentId"`
+}
+
+type vnicMetadata struct {
+	VnicID string `json:"vnicId"`
+}
+
+func (m *Metadata) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(m.BaseURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	httpClient := m.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot read instance metadata: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot read instance metadata %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode instance metadata %s: %w", path, err)
+	}
+	return nil
+}
+
+// CompartmentID returns the compartment of the instance.
+func (m *Metadata) CompartmentID(ctx context.Context) (string, error) {
+	var instance instanceMetadata
+	if err := m.get(ctx, "/instance/", &instance); err != nil {
+		return "", err
+	}
+	if instance.CompartmentID == "" {
+		return "", fmt.Errorf("instance metadata contains no compartment")
+	}
+	return instance.CompartmentID, nil
+}
+
+// VcnID returns the VCN of the instance's primary VNIC. The metadata service
+// only knows the VNIC, so its subnet and VCN are looked up with vnc.
+func (m *Metadata) VcnID(ctx context.Context, vnc *ocicore.VirtualNetworkClient) (string, error) {
+	var vnics []vnicMetadata
+	if err := m.get(ctx, "/vnics/", &vnics); err != nil {
+		return "", err
+	}
+	if len(vnics) == 0 || vnics[0].VnicID == "" {
+		return "", fmt.Errorf("instance metadata contains no VNIC")
+	}
+
+	vnic, err := vnc.GetVnic(ctx, ocicore.GetVnicRequest{VnicId: &vnics[0].VnicID})
+	if err != nil {
+		return "", fmt.Errorf("cannot get VNIC %s: %w", vnics[0].VnicID, err)
+	}
+	subnet, err := vnc.GetSubnet(ctx, ocicore.GetSubnetRequest{SubnetId: vnic.SubnetId})
+	if err != nil {
+		return "", fmt.Errorf("cannot get subnet %s: %w", *vnic.SubnetId, err)
+	}
+	return *subnet.VcnId, nil
+}
@@ -0,0 +1,140 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+)
+
+// metadataServer serves the given paths as JSON, like the instance metadata
+// service and the VirtualNetwork API do.
+func metadataServer(t *testing.T, responses map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCompartmentID(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "compartment of the instance",
+			responses: map[string]string{"/opc/v2/instance/": `{"compartmentId": "ocid1.compartment.oc1..test"}`},
+			want:      "ocid1.compartment.oc1..test",
+		},
+		{
+			name:      "no compartment",
+			responses: map[string]string{"/opc/v2/instance/": `{}`},
+			wantErr:   true,
+		},
+		{
+			name:      "metadata service unavailable",
+			responses: map[string]string{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := metadataServer(t, tt.responses)
+			m := &Metadata{BaseURL: server.URL + "/opc/v2/"}
+			got, err := m.CompartmentID(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompartmentID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CompartmentID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVcnID(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	provider := ocicommon.NewRawConfigurationProvider("ocid1.tenancy.oc1..test", "ocid1.user.oc1..test", "us-ashburn-1", "00:00", string(keyPEM), nil)
+
+	tests := []struct {
+		name      string
+		responses map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "VCN of the primary VNIC",
+			responses: map[string]string{
+				"/opc/v2/vnics/": `[{"vnicId": "ocid1.vnic.oc1..primary"}, {"vnicId": "ocid1.vnic.oc1..secondary"}]`,
+				"/20160918/vnics/ocid1.vnic.oc1..primary":  `{"id": "ocid1.vnic.oc1..primary", "subnetId": "ocid1.subnet.oc1..test"}`,
+				"/20160918/subnets/ocid1.subnet.oc1..test": `{"id": "ocid1.subnet.oc1..test", "vcnId": "ocid1.vcn.oc1..test"}`,
+			},
+			want: "ocid1.vcn.oc1..test",
+		},
+		{
+			name:      "no VNIC",
+			responses: map[string]string{"/opc/v2/vnics/": `[]`},
+			wantErr:   true,
+		},
+		{
+			name:      "VNIC not found",
+			responses: map[string]string{"/opc/v2/vnics/": `[{"vnicId": "ocid1.vnic.oc1..primary"}]`},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := metadataServer(t, tt.responses)
+			vnc, err := ocicore.NewVirtualNetworkClientWithConfigurationProvider(provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vnc.Host = server.URL
+			m := &Metadata{BaseURL: server.URL + "/opc/v2"}
+			got, err := m.VcnID(context.Background(), &vnc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VcnID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VcnID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+)
+
+// ClientOptions configures how the operator reaches the OCI API
+type ClientOptions struct {
+	// Endpoint replaces the regional VirtualNetwork API endpoint, e.g. to use
+	// a local simulator.
+	Endpoint string
+	// ProxyURL is the HTTP(S) proxy to use. If empty, the HTTPS_PROXY and
+	// NO_PROXY environment variables apply.
+	ProxyURL string
+	// CABundle is the path to a PEM file with additional CAs to trust.
+	CABundle string
+	// Timeout of each request to the API.
+	Timeout time.Duration
+}
+
+// Configure applies the options to vnc. The endpoint is only applied if
+// withEndpoint is set, as it is only valid for the operator's own region.
+func (o ClientOptions) Configure(vnc *ocicore.VirtualNetworkClient, withEndpoint bool) error {
+	if withEndpoint && o.Endpoint != "" {
+		vnc.Host = o.Endpoint
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.ProxyURL != "" {
+		proxyURL, err := url.Parse(o.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if o.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(o.CABundle)
+		if err != nil {
+			return fmt.Errorf("cannot read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA bundle %s", o.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	vnc.HTTPClient = &http.Client{
+		Timeout:   o.Timeout,
+		Transport: transport,
+	}
+	return nil
+}
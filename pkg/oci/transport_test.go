@@ -0,0 +1,141 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ocicore "github.com/oracle/oci-go-sdk/v31/core"
+)
+
+func TestClientOptionsConfigure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	caBundle := writeFile("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	empty := writeFile("empty.pem", []byte("no certificates"))
+
+	const regionalHost = "https://iaas.eu-frankfurt-1.oraclecloud.com"
+
+	tests := []struct {
+		name         string
+		options      ClientOptions
+		withEndpoint bool
+		wantHost     string
+		wantProxy    string
+		wantTLS      bool
+		wantErr      bool
+	}{
+		{
+			name:     "defaults",
+			wantHost: regionalHost,
+		},
+		{
+			name:         "endpoint",
+			options:      ClientOptions{Endpoint: "https://localhost:8443"},
+			withEndpoint: true,
+			wantHost:     "https://localhost:8443",
+		},
+		{
+			name:     "endpoint not applied to other regions",
+			options:  ClientOptions{Endpoint: "https://localhost:8443"},
+			wantHost: regionalHost,
+		},
+		{
+			name:      "proxy",
+			options:   ClientOptions{ProxyURL: "http://proxy.example.com:3128"},
+			wantHost:  regionalHost,
+			wantProxy: "http://proxy.example.com:3128",
+		},
+		{
+			name:    "invalid proxy",
+			options: ClientOptions{ProxyURL: "http://proxy.example.com:port"},
+			wantErr: true,
+		},
+		{
+			name:     "CA bundle",
+			options:  ClientOptions{CABundle: caBundle, Timeout: time.Second},
+			wantHost: regionalHost,
+			wantTLS:  true,
+		},
+		{
+			name:    "missing CA bundle",
+			options: ClientOptions{CABundle: filepath.Join(dir, "missing.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "CA bundle without certificates",
+			options: ClientOptions{CABundle: empty},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vnc := ocicore.VirtualNetworkClient{}
+			vnc.Host = regionalHost
+			err := tt.options.Configure(&vnc, tt.withEndpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if vnc.Host != tt.wantHost {
+				t.Errorf("host = %s, want %s", vnc.Host, tt.wantHost)
+			}
+
+			httpClient, ok := vnc.HTTPClient.(*http.Client)
+			if !ok {
+				t.Fatalf("HTTP client is a %T", vnc.HTTPClient)
+			}
+			if httpClient.Timeout != tt.options.Timeout {
+				t.Errorf("timeout = %s, want %s", httpClient.Timeout, tt.options.Timeout)
+			}
+			transport := httpClient.Transport.(*http.Transport)
+			if tt.wantProxy != "" {
+				req, _ := http.NewRequest(http.MethodGet, regionalHost, nil)
+				proxyURL, err := transport.Proxy(req)
+				if err != nil || proxyURL == nil || proxyURL.String() != tt.wantProxy {
+					t.Errorf("proxy = %v, %v, want %s", proxyURL, err, tt.wantProxy)
+				}
+			}
+
+			// only the CA bundle makes the client trust the test server
+			resp, err := httpClient.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err == nil) != tt.wantTLS && tt.wantProxy == "" {
+				t.Errorf("GET %s error = %v, want success %v", server.URL, err, tt.wantTLS)
+			}
+		})
+	}
+}
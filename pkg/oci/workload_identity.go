@@ -0,0 +1,279 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ocicommon "github.com/oracle/oci-go-sdk/v31/common"
+)
+
+const (
+	serviceAccountTokenPath  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCAPath     = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	workloadIdentityPort     = "12250"
+	workloadIdentityPath     = "/resourcePrincipalSessionTokens"
+	resourcePrincipalRegion  = "OCI_RESOURCE_PRINCIPAL_REGION"
+	serviceAccountCAPathEnv  = "OCI_KUBERNETES_SERVICE_ACCOUNT_CERT_PATH"
+	workloadIdentityTokenTTL = 5 * time.Minute // refresh tokens expiring sooner than this
+)
+
+// workloadIdentityProvider exchanges the pod's service account token for an
+// OCI resource principal session token at the OKE proxy, which runs on the
+// Kubernetes API server's address.
+type workloadIdentityProvider struct {
+	region     string
+	endpoint   string
+	tokenPath  string
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	privateKey *rsa.PrivateKey
+	token      string
+	claims     map[string]interface{}
+	expiresAt  time.Time
+}
+
+// NewWorkloadIdentityConfigurationProvider returns a configuration provider
+// for OKE workload identity. It needs to run in a pod on an enhanced OKE
+// cluster with OCI_RESOURCE_PRINCIPAL_REGION set.
+func NewWorkloadIdentityConfigurationProvider() (ocicommon.ConfigurationProvider, error) {
+	region := os.Getenv(resourcePrincipalRegion)
+	if region == "" {
+		return nil, fmt.Errorf("%s must be set for workload identity", resourcePrincipalRegion)
+	}
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	if host == "" {
+		return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST not set; workload identity only works in a pod")
+	}
+
+	caPath := os.Getenv(serviceAccountCAPathEnv)
+	if caPath == "" {
+		caPath = serviceAccountCAPath
+	}
+	ca, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", caPath)
+	}
+
+	return &workloadIdentityProvider{
+		region:    region,
+		endpoint:  "https://" + net.JoinHostPort(host, workloadIdentityPort) + workloadIdentityPath,
+		tokenPath: serviceAccountTokenPath,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+	}, nil
+}
+
+// refresh fetches a new session token if the current one expires soon. The
+// caller needs to hold p.mu.
+func (p *workloadIdentityProvider) refresh() error {
+	if p.token != "" && time.Until(p.expiresAt) > workloadIdentityTokenTTL {
+		return nil
+	}
+
+	saToken, err := ioutil.ReadFile(p.tokenPath)
+	if err != nil {
+		return fmt.Errorf("cannot read service account token: %w", err)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"podKey": base64.StdEncoding.EncodeToString(publicKey)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(saToken)))
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot get workload identity token: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot get workload identity token: %s: %s", resp.Status, respBody)
+	}
+
+	// the proxy returns base64 encoded JSON
+	decoded, err := base64.StdEncoding.DecodeString(string(respBody))
+	if err != nil {
+		return fmt.Errorf("cannot decode workload identity token response: %w", err)
+	}
+	var tokenResp struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(decoded, &tokenResp); err != nil {
+		return fmt.Errorf("cannot decode workload identity token response: %w", err)
+	}
+	token := strings.TrimPrefix(tokenResp.Token, "ST$")
+
+	claims, err := jwtClaims(token)
+	if err != nil {
+		return err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("workload identity token has no expiry")
+	}
+
+	p.privateKey = privateKey
+	p.token = token
+	p.claims = claims
+	p.expiresAt = time.Unix(int64(exp), 0)
+	return nil
+}
+
+// jwtClaims returns the claims of a JWT without verifying it
+func jwtClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed workload identity token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed workload identity token: %w", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed workload identity token: %w", err)
+	}
+	return claims, nil
+}
+
+// snapshot returns the private key and the session token it belongs to,
+// refreshing both if needed. They must be used together: a refresh replaces
+// both.
+func (p *workloadIdentityProvider) snapshot() (*rsa.PrivateKey, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.refresh(); err != nil {
+		return nil, "", err
+	}
+	return p.privateKey, "ST$" + p.token, nil
+}
+
+// Sign signs the request with a private key and session token of the same
+// snapshot. The SDK's default signer gets them with two calls, between which
+// a concurrent request could refresh them.
+func (p *workloadIdentityProvider) Sign(request *http.Request) error {
+	privateKey, keyID, err := p.snapshot()
+	if err != nil {
+		return err
+	}
+	return ocicommon.DefaultRequestSigner(sessionKey{privateKey: privateKey, keyID: keyID}).Sign(request)
+}
+
+// PrivateRSAKey refreshes the key and session token if needed. Only Sign
+// guarantees a matching KeyID; the SDK's default signer calls PrivateRSAKey
+// first, so KeyID doesn't refresh.
+func (p *workloadIdentityProvider) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	privateKey, _, err := p.snapshot()
+	return privateKey, err
+}
+
+func (p *workloadIdentityProvider) KeyID() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == "" {
+		if err := p.refresh(); err != nil {
+			return "", err
+		}
+	}
+	return "ST$" + p.token, nil
+}
+
+// sessionKey is a key provider for one private key and session token pair
+type sessionKey struct {
+	privateKey *rsa.PrivateKey
+	keyID      string
+}
+
+func (k sessionKey) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	return k.privateKey, nil
+}
+
+func (k sessionKey) KeyID() (string, error) {
+	return k.keyID, nil
+}
+
+func (p *workloadIdentityProvider) TenancyOCID() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.refresh(); err != nil {
+		return "", err
+	}
+	tenancy, ok := p.claims["res_tenant"].(string)
+	if !ok {
+		return "", fmt.Errorf("workload identity token has no tenancy")
+	}
+	return tenancy, nil
+}
+
+func (p *workloadIdentityProvider) UserOCID() (string, error) {
+	return "", nil
+}
+
+func (p *workloadIdentityProvider) KeyFingerprint() (string, error) {
+	return "", nil
+}
+
+func (p *workloadIdentityProvider) Region() (string, error) {
+	return p.region, nil
+}
+
+func (p *workloadIdentityProvider) AuthType() (ocicommon.AuthConfig, error) {
+	return ocicommon.AuthConfig{AuthType: ocicommon.UnknownAuthenticationType}, nil
+}
@@ -0,0 +1,280 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testJWT returns an unsigned JWT with the given claims
+func testJWT(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+// tokenResponse encodes a session token like the OKE proxy does
+func tokenResponse(token string) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"token": "ST$%s"}`, token)))
+}
+
+// workloadIdentityServer serves the responses of the OKE proxy in order,
+// repeating the last one, and records the pod keys it was sent.
+type workloadIdentityServer struct {
+	*httptest.Server
+	responses []string
+	status    int
+	podKeys   []string
+}
+
+func newWorkloadIdentityServer(t *testing.T, status int, responses ...string) (*workloadIdentityServer, *workloadIdentityProvider) {
+	s := &workloadIdentityServer{responses: responses, status: status}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != workloadIdentityPath || r.Header.Get("Authorization") != "Bearer sa-token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			PodKey string `json:"podKey"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.podKeys = append(s.podKeys, body.PodKey)
+
+		i := len(s.podKeys) - 1
+		if i >= len(s.responses) {
+			i = len(s.responses) - 1
+		}
+		w.WriteHeader(s.status)
+		_, _ = w.Write([]byte(s.responses[i]))
+	}))
+	t.Cleanup(s.Close)
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenPath, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return s, &workloadIdentityProvider{
+		region:     "us-ashburn-1",
+		endpoint:   s.URL + workloadIdentityPath,
+		tokenPath:  tokenPath,
+		httpClient: s.Client(),
+	}
+}
+
+func TestJWTClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			token: testJWT(t, map[string]interface{}{"res_tenant": "ocid1.tenancy.oc1..test", "exp": 1700000000}),
+			want:  map[string]interface{}{"res_tenant": "ocid1.tenancy.oc1..test", "exp": float64(1700000000)},
+		},
+		{
+			name:    "two parts",
+			token:   "eyJhbGciOiJub25lIn0.e30",
+			wantErr: true,
+		},
+		{
+			name:    "payload not base64",
+			token:   "eyJhbGciOiJub25lIn0.!!!.c2lnbmF0dXJl",
+			wantErr: true,
+		},
+		{
+			name:    "payload not JSON",
+			token:   "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte("not json")) + ".c2lnbmF0dXJl",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jwtClaims(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("jwtClaims() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jwtClaims() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkloadIdentityRefresh(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	token := testJWT(t, map[string]interface{}{"res_tenant": "ocid1.tenancy.oc1..test", "exp": expiresAt})
+
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  bool
+	}{
+		{
+			name:     "session token",
+			status:   http.StatusOK,
+			response: tokenResponse(token),
+		},
+		{
+			name:     "error response",
+			status:   http.StatusUnauthorized,
+			response: "service account not allowed",
+			wantErr:  true,
+		},
+		{
+			name:     "response not base64",
+			status:   http.StatusOK,
+			response: `{"token": "ST$` + token + `"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "response not JSON",
+			status:   http.StatusOK,
+			response: base64.StdEncoding.EncodeToString([]byte("token")),
+			wantErr:  true,
+		},
+		{
+			name:     "malformed token",
+			status:   http.StatusOK,
+			response: tokenResponse("not-a-jwt"),
+			wantErr:  true,
+		},
+		{
+			name:     "token without expiry",
+			status:   http.StatusOK,
+			response: tokenResponse(testJWT(t, map[string]interface{}{"res_tenant": "ocid1.tenancy.oc1..test"})),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, p := newWorkloadIdentityServer(t, tt.status, tt.response)
+
+			privateKey, keyID, err := p.snapshot()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("snapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if p.token != "" || p.privateKey != nil {
+					t.Errorf("failed refresh kept token %q", p.token)
+				}
+				return
+			}
+
+			if keyID != "ST$"+token {
+				t.Errorf("key ID = %q, want %q", keyID, "ST$"+token)
+			}
+			if got := publicKey(t, privateKey); got != server.podKeys[0] {
+				t.Errorf("pod key sent to the proxy doesn't belong to the private key")
+			}
+			if !p.expiresAt.Equal(time.Unix(expiresAt, 0)) {
+				t.Errorf("expiresAt = %s, want %s", p.expiresAt, time.Unix(expiresAt, 0))
+			}
+			if tenancy, err := p.TenancyOCID(); err != nil || tenancy != "ocid1.tenancy.oc1..test" {
+				t.Errorf("TenancyOCID() = %q, %v", tenancy, err)
+			}
+		})
+	}
+}
+
+func TestWorkloadIdentityExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		expiresIn   time.Duration
+		wantRefresh bool
+	}{
+		{name: "valid", expiresIn: time.Hour},
+		{name: "expiring soon", expiresIn: workloadIdentityTokenTTL - time.Minute, wantRefresh: true},
+		{name: "expired", expiresIn: -time.Minute, wantRefresh: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := testJWT(t, map[string]interface{}{"exp": time.Now().Add(tt.expiresIn).Unix()})
+			second := testJWT(t, map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+			server, p := newWorkloadIdentityServer(t, http.StatusOK, tokenResponse(first), tokenResponse(second))
+
+			firstKey, firstKeyID, err := p.snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			secondKey, secondKeyID, err := p.snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantKeyID, wantCalls := "ST$"+first, 1
+			if tt.wantRefresh {
+				wantKeyID, wantCalls = "ST$"+second, 2
+			}
+			if len(server.podKeys) != wantCalls {
+				t.Errorf("proxy called %d times, want %d", len(server.podKeys), wantCalls)
+			}
+			if secondKeyID != wantKeyID {
+				t.Errorf("key ID after refresh = %q, want %q", secondKeyID, wantKeyID)
+			}
+			if rotated := secondKey != firstKey; rotated != tt.wantRefresh {
+				t.Errorf("session key rotated = %v, want %v", rotated, tt.wantRefresh)
+			}
+			if tt.wantRefresh && (secondKeyID == firstKeyID || publicKey(t, secondKey) != server.podKeys[1]) {
+				t.Errorf("new token isn't used with the new session key")
+			}
+		})
+	}
+}
+
+func TestWorkloadIdentityMissingServiceAccountToken(t *testing.T) {
+	_, p := newWorkloadIdentityServer(t, http.StatusOK, tokenResponse(testJWT(t, map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})))
+	p.tokenPath = filepath.Join(t.TempDir(), "missing")
+
+	if _, err := p.PrivateRSAKey(); err == nil {
+		t.Error("PrivateRSAKey() without service account token succeeded")
+	}
+}
+
+// publicKey encodes the public key of the private key like the pod key sent
+// to the proxy
+func publicKey(t *testing.T, privateKey *rsa.PrivateKey) string {
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}